@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// The progress of a run, journaled to filePrefix_checkpoint.jsonl so that an interrupted run can be resumed.
+// The first line holds the options and the seed of the run, and every following line one query run (warm-up included).
+type checkpoint struct {
+	file *os.File
+	done map[roundKey]iterationRecord
+}
+
+type checkpointHeader struct {
+	Args []string `json:"args"` // the command line options of the run, nil for experiment files
+	Seed int64    `json:"seed"`
+}
+
+type roundKey struct {
+	n         int
+	p         float64
+	repeat    int
+	iteration int
+}
+
+type iterationRecord struct {
+	N         int     `json:"n"`
+	P         float64 `json:"p"`
+	Repeat    int     `json:"repeat"`
+	Iteration int     `json:"iteration"`
+	Timeout   bool    `json:"timeout,omitempty"`
+	Skipped   bool    `json:"skipped,omitempty"`
+}
+
+func (r iterationRecord) key() roundKey {
+	return roundKey{n: r.N, p: r.P, repeat: r.Repeat, iteration: r.Iteration}
+}
+
+func checkpointFile(filePrefix string) string {
+	return filePrefix + "_checkpoint.jsonl"
+}
+
+// Starts the journal of a new run
+func createCheckpoint(filePrefix string, args []string) *checkpoint {
+	file, err := os.Create(checkpointFile(filePrefix))
+	checkErr(err)
+	header, err := json.Marshal(checkpointHeader{Args: args, Seed: seed})
+	checkErr(err)
+	_, err = file.WriteString(string(header) + "\n")
+	checkErr(err)
+	return &checkpoint{file: file, done: make(map[roundKey]iterationRecord)}
+}
+
+// Reads the header of the journal of a run
+func readCheckpointHeader(filePrefix string) (checkpointHeader, error) {
+	file, err := os.Open(checkpointFile(filePrefix))
+	if err != nil {
+		return checkpointHeader{}, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return checkpointHeader{}, fmt.Errorf("%v has no header", checkpointFile(filePrefix))
+	}
+	var header checkpointHeader
+	err = json.Unmarshal(scanner.Bytes(), &header)
+	return header, err
+}
+
+// Reopens the journal of an interrupted run, restoring its seed
+func resumeCheckpoint(filePrefix string) *checkpoint {
+	header, err := readCheckpointHeader(filePrefix)
+	checkErr(err)
+	seed = header.Seed
+	utils.SetSeed(seed)
+
+	content, err := os.Open(checkpointFile(filePrefix))
+	checkErr(err)
+	defer content.Close()
+	done := make(map[roundKey]iterationRecord)
+	scanner := bufio.NewScanner(content)
+	scanner.Scan()
+	for scanner.Scan() {
+		var record iterationRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			break // the run was interrupted while writing this line
+		}
+		done[record.key()] = record
+	}
+	checkErr(scanner.Err())
+
+	file, err := reopenForAppend(checkpointFile(filePrefix), nil)
+	checkErr(err)
+	return &checkpoint{file: file, done: done}
+}
+
+// Reopens a file of an interrupted run to append to it, first cutting off the partial record the run may have been writing,
+// so that new records are not glued to it. Records end with the last line for which complete returns true, or with any line if complete is nil.
+func reopenForAppend(name string, complete func(line string) bool) (*os.File, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	end := 0
+	for start := 0; start < len(content); {
+		newline := bytes.IndexByte(content[start:], '\n')
+		if newline == -1 {
+			break
+		}
+		if complete == nil || complete(string(content[start:start+newline])) {
+			end = start + newline + 1
+		}
+		start += newline + 1
+	}
+	if end < len(content) {
+		if err := os.Truncate(name, int64(end)); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+}
+
+// Whether the given query run was completed before the run was interrupted. Always false without a journal.
+func (progress *checkpoint) completed(key roundKey) bool {
+	if progress == nil {
+		return false
+	}
+	_, done := progress.done[key]
+	return done
+}
+
+// Whether every measured query of a round was completed
+func (progress *checkpoint) roundCompleted(c cell, repeat int) bool {
+	for i := 0; i < graphRepeats; i++ {
+		if !progress.completed(roundKey{n: c.n, p: c.p, repeat: repeat, iteration: i}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (progress *checkpoint) record(record iterationRecord) {
+	if progress == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	checkErr(err)
+	_, err = progress.file.WriteString(string(line) + "\n")
+	checkErr(err)
+	progress.done[record.key()] = record
+}
+
+// Feeds the timeouts of the measured runs of the journal to the cutoff policy, in the order the queries were run
+func (progress *checkpoint) replay(rounds []round, policy *cutoffPolicy) {
+	for _, r := range rounds {
+		for i := warmup; i < graphRepeats; i++ {
+			if record, done := progress.done[roundKey{n: r.c.n, p: r.c.p, repeat: r.repeat, iteration: i}]; done && !record.Skipped {
+				policy.record(r.c, record.Timeout)
+			}
+		}
+	}
+}
+
+func (progress *checkpoint) Close() error {
+	return progress.file.Close()
+}
+
+// Returns the seed of the random choices made for one query run (iteration -1 for the graph of the round),
+// derived from the seed of the run so that any part of the run can be reproduced on its own
+func roundSeed(c cell, repeat int, iteration int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%v/%v/%v/%v/%v", seed, c.n, c.p, repeat, iteration)
+	return int64(h.Sum64())
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// A corpus of graphs kept in a directory, so that runs of different times can share their instances :
+// the graphs are stored in dir/graphs as runs store theirs (see storeGraph), and dir/corpus.jsonl holds one line per instance,
+// telling how it was generated and the answers of the queries that have a reference solver.
+// Random graphs are generated undirected, and loaded in neo4j and memgraph as in paired runs (see utils.PairedView).
+type graphCorpus struct {
+	dir       string
+	instances map[corpusKey]corpusInstance
+}
+
+type corpusKey struct {
+	family utils.GraphFamily
+	n      int
+	p      float64
+	repeat int
+}
+
+type corpusInstance struct {
+	Hash      string            `json:"hash"`
+	Family    utils.GraphFamily `json:"family"`
+	N         int               `json:"n"`
+	P         float64           `json:"p"` // -1 for families that do not depend on it
+	Repeat    int               `json:"repeat"`
+	Seed      int64             `json:"seed"` // the seed the graph was generated with
+	Endpoints string            `json:"endpoints"`
+	Edges     int               `json:"edges"`
+	Answers   map[string]bool   `json:"answers,omitempty"` // by query, for the queries without randomized parameters whose reference solver could solve the graph
+	Source    string            `json:"source,omitempty"`  // the file imported graphs were read from
+}
+
+func (inst corpusInstance) key() corpusKey {
+	return corpusKey{family: inst.Family, n: inst.N, p: inst.P, repeat: inst.Repeat}
+}
+
+func corpusManifest(dir string) string {
+	return filepath.Join(dir, "corpus.jsonl")
+}
+
+// Opens the corpus in dir. A directory without a corpus is an empty corpus if create is set, and an error otherwise.
+func openCorpus(dir string, create bool) (*graphCorpus, error) {
+	c := &graphCorpus{dir: dir, instances: make(map[corpusKey]corpusInstance)}
+	file, err := os.Open(corpusManifest(dir))
+	if errors.Is(err, os.ErrNotExist) && create {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var inst corpusInstance
+		if err := json.Unmarshal(scanner.Bytes(), &inst); err != nil {
+			return nil, fmt.Errorf("%v : %w", corpusManifest(dir), err)
+		}
+		c.instances[inst.key()] = inst
+	}
+	return c, scanner.Err()
+}
+
+// Returns the instances of the corpus, by family, edge probability, order and repeat
+func (c *graphCorpus) list() []corpusInstance {
+	instances := make([]corpusInstance, 0, len(c.instances))
+	for _, inst := range c.instances {
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		a, b := instances[i], instances[j]
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		if a.P != b.P {
+			return a.P < b.P
+		}
+		if a.N != b.N {
+			return a.N < b.N
+		}
+		return a.Repeat < b.Repeat
+	})
+	return instances
+}
+
+// Returns the instance whose hash starts with prefix
+func (c *graphCorpus) find(prefix string) (corpusInstance, error) {
+	matches := make([]corpusInstance, 0)
+	for _, inst := range c.instances {
+		if strings.HasPrefix(inst.Hash, prefix) {
+			matches = append(matches, inst)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return corpusInstance{}, fmt.Errorf("%v has no graph %v", c.dir, prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return corpusInstance{}, fmt.Errorf("%v names %v graphs of %v", prefix, len(matches), c.dir)
+	}
+}
+
+// Returns the graph of an instance
+func (c *graphCorpus) graph(inst corpusInstance) (*utils.Graph, error) {
+	return utils.LoadGraph(filepath.Join(c.dir, "graphs"), inst.Hash)
+}
+
+// Returns the graph of family for a round
+func (c *graphCorpus) roundGraph(family utils.GraphFamily, cl cell, repeat int) (*utils.Graph, error) {
+	inst, found := c.instances[corpusKey{family: family, n: cl.n, p: cl.p, repeat: repeat}]
+	if !found {
+		return nil, fmt.Errorf("the corpus %v has no %v graph for n=%v, p=%v, repeat %v", c.dir, family, cl.n, cl.p, repeat)
+	}
+	return c.graph(inst)
+}
+
+// Returns an error naming the first round of family whose instance the corpus lacks, for the given cells and repeats. A nil corpus lacks nothing.
+func (c *graphCorpus) covers(family utils.GraphFamily, cells []cell, repeats int) error {
+	if c == nil {
+		return nil
+	}
+	for _, cl := range cells {
+		for repeat := 0; repeat < repeats; repeat++ {
+			if _, found := c.instances[corpusKey{family: family, n: cl.n, p: cl.p, repeat: repeat}]; !found {
+				_, err := c.roundGraph(family, cl, repeat)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Generates the graphs of family for every cell and repeat that the corpus lacks, stores them and adds them to the manifest.
+// Returns how many graphs were added.
+func (c *graphCorpus) generate(family utils.GraphFamily, cells []cell, repeats int) (int, error) {
+	generator, err := corpusGenerator(family)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(corpusManifest(c.dir), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	added := 0
+	for _, cl := range cells {
+		for repeat := 0; repeat < repeats; repeat++ {
+			key := corpusKey{family: family, n: cl.n, p: cl.p, repeat: repeat}
+			if _, found := c.instances[key]; found {
+				continue
+			}
+			fmt.Printf("\rGenerating %v graphs : p=%v, n=%v (repeat %v)", family, cl.p, cl.n, repeat+1)
+			graphSeed := roundSeed(cl, repeat, -1)
+			utils.SetSeed(graphSeed)
+			g := generator(cl.n, cl.p, endpoints)
+			hash, err := utils.SaveGraph(filepath.Join(c.dir, "graphs"), g)
+			if err != nil {
+				return added, err
+			}
+			inst := corpusInstance{Hash: hash, Family: family, N: cl.n, P: cl.p, Repeat: repeat, Seed: graphSeed, Endpoints: string(endpoints), Edges: len(g.Edges), Answers: knownAnswers(g)}
+			line, err := json.Marshal(inst)
+			if err != nil {
+				return added, err
+			}
+			if _, err := file.WriteString(string(line) + "\n"); err != nil {
+				return added, err
+			}
+			c.instances[key] = inst
+			added++
+		}
+	}
+	if added > 0 {
+		fmt.Println()
+	}
+	return added, nil
+}
+
+// Returns the generator of the graphs of family f in a corpus, whichever backend they are loaded in
+func corpusGenerator(f utils.GraphFamily) (utils.GraphGenerator, error) {
+	backends := make([]utils.Backend, 0)
+	for _, b := range []utils.Backend{utils.Neo4j, utils.Memgraph, utils.Postgres, utils.DuckDB} {
+		if _, ok := f.Generator(b); ok {
+			backends = append(backends, b)
+		}
+	}
+	return utils.PairedGenerator(f, backends)
+}
+
+// Returns the answers of the reference solvers on g, for the queries of its family that pick no nodes.
+// Some solvers take quadratic time, so graphs of more than maxAnsweredEdges edges get no answers.
+func knownAnswers(g *utils.Graph) map[string]bool {
+	answers := make(map[string]bool)
+	if len(g.Edges) > maxAnsweredEdges {
+		return answers
+	}
+	for _, q := range utils.Catalog {
+		if q.Graph() != g.Family || len(q.Parameters()) > 0 {
+			continue
+		}
+		if oracle, hasOracle := utils.FindOracle(q.Name()); hasOracle {
+			if found, solved := oracle(g, nil); solved {
+				answers[q.Name()] = found
+			}
+		}
+	}
+	return answers
+}
+
+const maxAnsweredEdges = 100000
+
+// Adds the graph of a file to the corpus, as the next repeat of its family and size, with an edge probability of -1.
+// Returns false if the corpus already holds the same graph.
+func (c *graphCorpus) add(g *utils.Graph, source string) (corpusInstance, bool, error) {
+	hash := utils.GraphHash(g)
+	for _, inst := range c.instances {
+		if inst.Hash == hash {
+			return inst, false, nil
+		}
+	}
+	if _, err := utils.SaveGraph(filepath.Join(c.dir, "graphs"), g); err != nil {
+		return corpusInstance{}, false, err
+	}
+	inst := corpusInstance{Hash: hash, Family: g.Family, N: g.Nodes, P: -1, Seed: seed, Endpoints: string(endpoints), Edges: len(g.Edges), Answers: knownAnswers(g), Source: source}
+	for {
+		if _, found := c.instances[inst.key()]; !found {
+			break
+		}
+		inst.Repeat++
+	}
+	line, err := json.Marshal(inst)
+	if err != nil {
+		return inst, false, err
+	}
+	file, err := os.OpenFile(corpusManifest(c.dir), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return inst, false, err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(string(line) + "\n"); err != nil {
+		return inst, false, err
+	}
+	c.instances[inst.key()] = inst
+	return inst, true, nil
+}
+
+// Returns the graph of a round : the instance of the corpus if the run draws from one, or else a newly generated graph
+func roundGraph(generator utils.GraphGenerator, c cell, repeat int) *utils.Graph {
+	if corpus != nil {
+		g, err := corpus.roundGraph(query.Graph(), c, repeat)
+		checkErr(err)
+		return g
+	}
+	utils.SetSeed(roundSeed(c, repeat, -1))
+	return generator(c.n, c.p, endpoints)
+}
+
+// Generates, lists or inspects a corpus of graphs
+func corpusCommand(args []string) {
+	if len(args) == 0 {
+		panic(errors.New("please choose a corpus command : generate, import, list or inspect"))
+	}
+	switch args[0] {
+	case "generate":
+		generateCorpus(args[1:])
+	case "import":
+		importCorpus(args[1:])
+	case "list":
+		listCorpus(args[1:])
+	case "inspect":
+		inspectCorpus(args[1:])
+	default:
+		panic(fmt.Errorf("unknown corpus command %v, expected generate, import, list or inspect", args[0]))
+	}
+}
+
+func generateCorpus(args []string) {
+	flags := flag.NewFlagSet("corpus generate", flag.ExitOnError)
+	dirFlag := flags.String("dir", "corpus", "The directory of the corpus")
+	familyFlag := flags.String("family", "", "The family of the graphs : random, labeled, doubleLine, edgeValue or nodeValue")
+	nFlag := flags.String("n", "10:100:10", "The graph sizes. Either a list (10,20,50), a linear range (10:300:10), a geometric range (10:1000:x2) or a log scale range with a number of points (10:1000:log5)")
+	pFlag := flags.String("p", "0.1:1.0:0.1", "The edge probabilities, for the families that depend on it. Same syntax as -n")
+	repeatsFlag := flags.Int("repeats", 5, "How many graphs to generate for each size and edge probability")
+	seedFlag := flags.Int64("seed", -1, "A seed for the rng. Will be generated using current time if ommited")
+	endpointsFlag := flags.String("endpoints", "uniform", fmt.Sprintf("How the start/end nodes of graphs are picked. One of %v", utils.EndpointStrategies))
+	checkErr(flags.Parse(args))
+
+	family := utils.GraphFamily(*familyFlag)
+	if _, err := corpusGenerator(family); err != nil {
+		panic(fmt.Errorf("%v is not a graph family : %w", *familyFlag, err))
+	}
+	var err error
+	endpoints, err = utils.ParseEndpointStrategy(*endpointsFlag)
+	checkErr(err)
+	corpusSweeps := make(map[string]sweepSpec)
+	corpusSweeps["n"], err = parseSweep(*nFlag)
+	checkErr(err)
+	corpusSweeps["p"], err = parseSweep(*pFlag)
+	checkErr(err)
+	cells, err := sweepCells(family, corpusSweeps)
+	checkErr(err)
+	initRandSeed(seedFlag)
+
+	c, err := openCorpus(*dirFlag, true)
+	checkErr(err)
+	added, err := c.generate(family, cells, *repeatsFlag)
+	checkErr(err)
+	fmt.Printf("Added %v graphs to %v (seed %v), which now holds %v graphs\n", added, *dirFlag, seed, len(c.instances))
+}
+
+func importCorpus(args []string) {
+	flags := flag.NewFlagSet("corpus import", flag.ExitOnError)
+	dirFlag := flags.String("dir", "corpus", "The directory of the corpus")
+	formatFlag := flags.String("format", "", fmt.Sprintf("The format of the files, one of %v. Guessed from their extension by default", utils.GraphFormats))
+	familyFlag := flags.String("family", "", "The family of the graphs : random, labeled, doubleLine, edgeValue or nodeValue. Inferred from their labels, weights and node values by default")
+	undirectedFlag := flags.Bool("undirected", false, "Read the graphs as undirected. Edge lists are read as directed otherwise")
+	seedFlag := flags.Int64("seed", -1, "A seed for the rng, which picks the start/end nodes of graphs that name none. Will be generated using current time if ommited")
+	endpointsFlag := flags.String("endpoints", "uniform", fmt.Sprintf("How the start/end nodes of graphs that name none are picked. One of %v", utils.EndpointStrategies))
+	checkErr(flags.Parse(args))
+	if flags.NArg() == 0 {
+		panic(errors.New("please give the graph files to import"))
+	}
+
+	options := utils.ImportOptions{Family: utils.GraphFamily(*familyFlag), Undirected: *undirectedFlag}
+	var err error
+	if *formatFlag != "" {
+		options.Format, err = utils.ParseGraphFormat(*formatFlag)
+		checkErr(err)
+	}
+	endpoints, err = utils.ParseEndpointStrategy(*endpointsFlag)
+	checkErr(err)
+	options.Endpoints = endpoints
+	initRandSeed(seedFlag)
+
+	c, err := openCorpus(*dirFlag, true)
+	checkErr(err)
+	checkErr(os.MkdirAll(c.dir, 0755))
+	for _, name := range flags.Args() {
+		g, err := utils.ImportGraphFile(name, options)
+		checkErr(err)
+		inst, added, err := c.add(g, filepath.Base(name))
+		checkErr(err)
+		if !added {
+			fmt.Printf("%v : already in the corpus as %v\n", name, inst.Hash)
+			continue
+		}
+		fmt.Printf("%v : %v graph of %v nodes and %v edges, n=%v, p=%v, repeat %v, %v\n", name, inst.Family, inst.N, inst.Edges, inst.N, inst.P, inst.Repeat, inst.Hash)
+	}
+}
+
+func listCorpus(args []string) {
+	flags := flag.NewFlagSet("corpus list", flag.ExitOnError)
+	dirFlag := flags.String("dir", "corpus", "The directory of the corpus")
+	familyFlag := flags.String("family", "", "Only list graphs of this family")
+	checkErr(flags.Parse(args))
+
+	c, err := openCorpus(*dirFlag, false)
+	checkErr(err)
+	fmt.Printf("%-16v  %-10v  %5v  %4v  %6v  %7v  %v\n", "hash", "family", "n", "p", "repeat", "edges", "answers")
+	for _, inst := range c.list() {
+		if *familyFlag != "" && string(inst.Family) != *familyFlag {
+			continue
+		}
+		fmt.Printf("%-16v  %-10v  %5v  %4v  %6v  %7v  %v\n", inst.Hash, inst.Family, inst.N, inst.P, inst.Repeat, inst.Edges, formatAnswers(inst.Answers))
+	}
+}
+
+func formatAnswers(answers map[string]bool) string {
+	names := make([]string, 0, len(answers))
+	for name := range answers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%v=%v", name, answers[name])
+	}
+	return strings.Join(names, " ")
+}
+
+func inspectCorpus(args []string) {
+	flags := flag.NewFlagSet("corpus inspect", flag.ExitOnError)
+	dirFlag := flags.String("dir", "corpus", "The directory of the corpus")
+	scriptFlag := flags.String("script", "", "Print the script loading the graph in this system instead of its edge list : neo4j, memgraph, postgres or duckDB")
+	checkErr(flags.Parse(args))
+	if flags.NArg() != 1 {
+		panic(errors.New("please give the hash of the graph to inspect, or its first characters"))
+	}
+
+	c, err := openCorpus(*dirFlag, false)
+	checkErr(err)
+	inst, err := c.find(flags.Arg(0))
+	checkErr(err)
+	g, err := c.graph(inst)
+	checkErr(err)
+	fmt.Printf("graph %v\nfamily %v, n=%v, p=%v, repeat %v, seed %v, %v endpoints\n", inst.Hash, inst.Family, inst.N, inst.P, inst.Repeat, inst.Seed, inst.Endpoints)
+	if inst.Source != "" {
+		fmt.Printf("imported from %v\n", inst.Source)
+	}
+	minDegree, maxDegree := degreeRange(g)
+	fmt.Printf("%v edges, degrees from %v to %v, start %v, end %v\n", len(g.Edges), minDegree, maxDegree, g.Start, g.End)
+	if len(inst.Answers) > 0 {
+		fmt.Printf("answers : %v\n", formatAnswers(inst.Answers))
+	}
+	fmt.Println()
+	if *scriptFlag != "" {
+		b := utils.Backend(*scriptFlag)
+		if _, ok := inst.Family.Generator(b); !ok && inst.Family != utils.RandomGraph {
+			panic(fmt.Errorf("there is no %v graph for %v", inst.Family, b))
+		}
+		fmt.Println(strings.Join(utils.GraphScript(b, utils.PairedView(g, b)), "\n"))
+		return
+	}
+	checkErr(utils.EncodeGraph(os.Stdout, g))
+}
+
+// Returns the smallest and largest number of edges of a node of g, counting the edges of undirected graphs once
+func degreeRange(g *utils.Graph) (int, int) {
+	degrees := make([]int, g.Nodes)
+	for _, e := range g.Edges {
+		if !g.Undirected || e.Src <= e.Trg {
+			degrees[e.Src]++
+			if e.Src != e.Trg {
+				degrees[e.Trg]++
+			}
+		}
+	}
+	if len(degrees) == 0 {
+		return 0, 0
+	}
+	sort.Ints(degrees)
+	return degrees[0], degrees[len(degrees)-1]
+}
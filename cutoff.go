@@ -0,0 +1,34 @@
+package main
+
+// Skips the configurations that are dominated by a configuration that keeps timing out :
+// once limit queries in a row time out on graphs of some size, larger graphs with the same edge probability are not tested.
+type cutoffPolicy struct {
+	limit         int             // 0 disables the policy
+	streaks       map[cell]int    // number of queries in a row that timed out for each configuration
+	dominatedFrom map[float64]int // by edge probability, the size above which graphs are skipped
+}
+
+func newCutoffPolicy(limit int) *cutoffPolicy {
+	return &cutoffPolicy{limit: limit, streaks: make(map[cell]int), dominatedFrom: make(map[float64]int)}
+}
+
+// Records whether a query on a graph of configuration c timed out
+func (policy *cutoffPolicy) record(c cell, timedOut bool) {
+	if policy.limit == 0 {
+		return
+	}
+	if !timedOut {
+		policy.streaks[c] = 0
+		return
+	}
+	policy.streaks[c]++
+	if from, ok := policy.dominatedFrom[c.p]; policy.streaks[c] >= policy.limit && (!ok || c.n < from) {
+		policy.dominatedFrom[c.p] = c.n
+	}
+}
+
+// Whether configuration c should be skipped
+func (policy *cutoffPolicy) dominated(c cell) bool {
+	from, ok := policy.dominatedFrom[c.p]
+	return ok && c.n > from
+}
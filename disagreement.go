@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// Compares the answers of the backends of a paired run on every instance, a graph and the nodes picked for one of its query runs,
+// along with the answer of the reference solver of the query if there is one. Runs on a single backend are compared with the solver only.
+// Instances answered differently are written to filePrefix_disagreements.csv, with a reproducer in the filePrefix_disagreements directory.
+type disagreementDetector struct {
+	filePrefix string
+	file       *os.File
+	systems    []utils.Backend
+	oracle     utils.Oracle // nil if the query has no reference solver
+	instances  map[int]*instance
+}
+
+// What the backends answered on the instance of one iteration of the current round
+type instance struct {
+	params  utils.QueryParams
+	answers map[utils.Backend]bool
+	scripts map[utils.Backend][]string
+	queries map[utils.Backend]string
+}
+
+func disagreementFile(filePrefix string) string {
+	return filePrefix + "_disagreements.csv"
+}
+
+// Creates the disagreement file of a run, or reopens it to append to it when resuming
+func newDisagreementDetector(filePrefix string, systems []utils.Backend, resuming bool) *disagreementDetector {
+	detector := &disagreementDetector{filePrefix: filePrefix, systems: systems, instances: make(map[int]*instance)}
+	detector.oracle, _ = utils.FindOracle(query.Name())
+	if resuming && fileExists(disagreementFile(filePrefix)) {
+		file, err := reopenForAppend(disagreementFile(filePrefix), nil)
+		checkErr(err)
+		detector.file = file
+		return detector
+	}
+	file, err := os.Create(disagreementFile(filePrefix))
+	checkErr(err)
+	header := "graph,iteration"
+	for _, param := range query.Parameters() {
+		header += "," + param
+	}
+	for _, system := range systems {
+		header += "," + string(system)
+	}
+	_, err = file.WriteString(header + ",oracle,reproducer\n")
+	checkErr(err)
+	detector.file = file
+	return detector
+}
+
+// Records the answer of the current backend to one iteration of the round. Runs without an answer are ignored.
+func (detector *disagreementDetector) observe(iteration int, params utils.QueryParams, createGraphQuery []string, queryString string, qRes utils.QueryResult) {
+	if detector == nil || qRes.QExecTime < 0 {
+		return
+	}
+	inst, found := detector.instances[iteration]
+	if !found {
+		inst = &instance{params: params, answers: make(map[utils.Backend]bool), scripts: make(map[utils.Backend][]string), queries: make(map[utils.Backend]string)}
+		detector.instances[iteration] = inst
+	}
+	inst.answers[backend] = qRes.Found
+	inst.scripts[backend] = createGraphQuery
+	inst.queries[backend] = queryString
+}
+
+// Compares the answers to every instance of the round on graph, then forgets them
+func (detector *disagreementDetector) check(c cell, repeat int, graph *utils.Graph) {
+	if detector == nil {
+		return
+	}
+	iterations := make([]int, 0, len(detector.instances))
+	for i := range detector.instances {
+		iterations = append(iterations, i)
+	}
+	sort.Ints(iterations)
+	for _, i := range iterations {
+		inst := detector.instances[i]
+		answers := make(map[bool]bool)
+		for _, found := range inst.answers {
+			answers[found] = true
+		}
+		oracle := ""
+		if detector.oracle != nil {
+			if found, ok := detector.oracle(graph, inst.params); ok {
+				answers[found] = true
+				oracle = fmt.Sprint(found)
+			}
+		}
+		if len(answers) > 1 {
+			detector.report(c, repeat, i, inst, oracle)
+		}
+	}
+	detector.instances = make(map[int]*instance)
+}
+
+// Writes an instance answered differently to the disagreement file, along with its reproducer
+func (detector *disagreementDetector) report(c cell, repeat int, iteration int, inst *instance, oracle string) {
+	id := graphID(c, repeat)
+	reproducer := filepath.Join(detector.filePrefix+"_disagreements", fmt.Sprintf("%v_i%v.txt", id, iteration+1))
+	checkErr(os.MkdirAll(filepath.Dir(reproducer), 0755))
+
+	row := fmt.Sprintf("%v,%v", id, iteration+1)
+	for _, param := range inst.params {
+		row += fmt.Sprintf(",%v", param)
+	}
+	summaryLine := make([]string, 0)
+	for _, system := range detector.systems {
+		answer := ""
+		if found, ok := inst.answers[system]; ok {
+			answer = fmt.Sprint(found)
+			summaryLine = append(summaryLine, fmt.Sprintf("%v=%v", system, found))
+		}
+		row += "," + answer
+	}
+	if oracle != "" {
+		summaryLine = append(summaryLine, "oracle="+oracle)
+	}
+	_, err := detector.file.WriteString(fmt.Sprintf("%v,%v,%v\n", row, oracle, reproducer))
+	checkErr(err)
+
+	content := fmt.Sprintf("%v on graph %v (seed %v), iteration %v", query.Name(), id, roundSeed(c, repeat, -1), iteration+1)
+	if len(inst.params) > 0 {
+		content += fmt.Sprintf(", nodes %v", inst.params)
+	}
+	content += fmt.Sprintf("\nanswers : %v\n", strings.Join(summaryLine, " "))
+	for _, system := range detector.systems {
+		if _, ok := inst.answers[system]; !ok {
+			continue
+		}
+		content += fmt.Sprintf("\n== %v ==\n%v\n\n%v\n", system, strings.Join(inst.scripts[system], "\n"), inst.queries[system])
+	}
+	checkErr(os.WriteFile(reproducer, []byte(content), 0644))
+	summary.disagreements = append(summary.disagreements, reproducer)
+}
+
+func (detector *disagreementDetector) Close() error {
+	return detector.file.Close()
+}
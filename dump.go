@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// An entry of a dump file : a query run, along with its graph and the text of the query.
+// Current dumps reference the graph by its hash in the graph directory of the run, older ones hold the script creating it.
+// Older dumps only have the order, edge probability, execution time, found and timestamp columns.
+type dumpEntry struct {
+	index     int    // of the entry in the file, from 1
+	graphID   string // paired runs only
+	system    string // named by the dump, or by each row in paired runs. Empty in older dumps
+	graphHash string // current dumps only
+	n         int
+	p         float64
+	result    utils.QueryResult // Err holds the recorded error message
+	timestamp string
+	warmup    bool
+	retries   int
+	script    []string
+	query     string
+}
+
+// Returns the execution time or code of an outcome written to results, see outcome
+func parseOutcome(s string) (int, error) {
+	for code := -5; code <= -1; code++ {
+		if s == outcome(utils.QueryResult{QExecTime: code}) {
+			return code, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// Reads every entry of a dump file, along with the seed of its run
+func readDump(name string) (int64, []dumpEntry, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024) // SQL scripts of double line graphs are written on one line
+
+	var seed int64
+	if scanner.Scan() {
+		if _, err := fmt.Sscanf(scanner.Text(), "seed = %d", &seed); err != nil {
+			return 0, nil, fmt.Errorf("%v : missing seed line", name)
+		}
+	}
+	entries := make([]dumpEntry, 0)
+	system := ""
+	lineNumber := 1
+	for scanner.Scan() {
+		lineNumber++
+		if scanner.Text() == "" {
+			continue
+		}
+		if strings.HasPrefix(scanner.Text(), "system = ") {
+			system = strings.TrimPrefix(scanner.Text(), "system = ")
+			continue
+		}
+		entry, err := parseDumpRow(scanner.Text())
+		if err != nil {
+			return seed, entries, fmt.Errorf("%v:%v : %w", name, lineNumber, err)
+		}
+		entry.index = len(entries) + 1
+		if entry.system == "" {
+			entry.system = system
+		}
+		// The script ends with an empty line, followed by the query and the separator
+		for scanner.Scan() && scanner.Text() != "" {
+			lineNumber++
+			entry.script = append(entry.script, scanner.Text())
+		}
+		query := make([]string, 0)
+		complete := false
+		for !complete && scanner.Scan() {
+			lineNumber++
+			complete = scanner.Text() == "------"
+			if !complete {
+				query = append(query, scanner.Text())
+			}
+		}
+		lineNumber++   // the empty line ending the script
+		if !complete { // the run was stopped while writing its last entry
+			break
+		}
+		entry.query = strings.TrimSpace(strings.Join(query, "\n"))
+		entries = append(entries, entry)
+	}
+	return seed, entries, scanner.Err()
+}
+
+// Parses the first line of a dump entry : the columns of the result file, without the parameters of the query
+func parseDumpRow(row string) (dumpEntry, error) {
+	var entry dumpEntry
+	fields, err := csv.NewReader(strings.NewReader(row)).Read()
+	if err != nil {
+		return entry, err
+	}
+	if len(fields) > 0 {
+		if _, err := strconv.Atoi(fields[0]); err != nil && len(fields) > 2 { // paired runs start with the graph id and the system
+			entry.graphID, entry.system = fields[0], fields[1]
+			fields = fields[2:]
+		}
+	}
+	if len(fields) < 5 {
+		return entry, fmt.Errorf("expected at least 5 columns, got %q", row)
+	}
+	if entry.n, err = strconv.Atoi(fields[0]); err != nil {
+		return entry, err
+	}
+	if entry.p, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return entry, err
+	}
+	if entry.result.QExecTime, err = parseOutcome(fields[2]); err != nil {
+		return entry, err
+	}
+	if entry.result.Found, err = strconv.ParseBool(fields[3]); err != nil {
+		return entry, err
+	}
+	entry.timestamp = fields[4]
+	if len(fields) >= 8 {
+		if entry.warmup, err = strconv.ParseBool(fields[5]); err != nil {
+			return entry, err
+		}
+		if fields[6] != "" {
+			entry.result.Err = fmt.Errorf("%v", fields[6])
+		}
+		if entry.retries, err = strconv.Atoi(fields[7]); err != nil {
+			return entry, err
+		}
+	}
+	if len(fields) >= 9 {
+		entry.graphHash = fields[8]
+	}
+	return entry, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+func TestParseDumpRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		row   string
+		entry dumpEntry
+	}{
+		{"old", "2,0.1,5,true,11:54:04",
+			dumpEntry{n: 2, p: 0.1, result: utils.QueryResult{QExecTime: 5, Found: true}, timestamp: "11:54:04"}},
+		{"old timeout", "30,0.5,-1,false,12:00:00",
+			dumpEntry{n: 30, p: 0.5, result: utils.QueryResult{QExecTime: -1}, timestamp: "12:00:00"}},
+		{"without graph hash", "10,0.3,timeout,false,15:04:05,true,,2",
+			dumpEntry{n: 10, p: 0.3, result: utils.QueryResult{QExecTime: -1}, timestamp: "15:04:05", warmup: true, retries: 2}},
+		{"current", "5,-1,1,false,15:16:07,false,,0,28b9c28f638f41bb",
+			dumpEntry{n: 5, p: -1, result: utils.QueryResult{QExecTime: 1}, timestamp: "15:16:07", graphHash: "28b9c28f638f41bb"}},
+		{"current error", `5,0.2,error,false,15:16:07,false,"syntax error, at ""x""",1,28b9c28f638f41bb`,
+			dumpEntry{n: 5, p: 0.2, result: utils.QueryResult{QExecTime: -5, Err: errors.New(`syntax error, at "x"`)}, timestamp: "15:16:07", retries: 1, graphHash: "28b9c28f638f41bb"}},
+		{"paired", "n5-p0.5-r1,memgraph,5,0.5,outOfMemory,false,15:16:07,false,,0,28b9c28f638f41bb",
+			dumpEntry{graphID: "n5-p0.5-r1", system: "memgraph", n: 5, p: 0.5, result: utils.QueryResult{QExecTime: -2}, timestamp: "15:16:07", graphHash: "28b9c28f638f41bb"}},
+		{"paired skipped", "n5-p-1-r0,duckDB,5,-1,skipped,false,15:16:07,true,,0,",
+			dumpEntry{graphID: "n5-p-1-r0", system: "duckDB", n: 5, p: -1, result: utils.QueryResult{QExecTime: -3}, timestamp: "15:16:07", warmup: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := parseDumpRow(test.row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entry, test.entry) {
+				t.Errorf("got %+v, want %+v", entry, test.entry)
+			}
+		})
+	}
+}
+
+func TestParseInvalidDumpRow(t *testing.T) {
+	for _, row := range []string{"", "2,0.1,5,true", "x,0.1,5,true,11:54:04", "2,0.1,slow,true,11:54:04", "2,0.1,5,yes,11:54:04", "2,0.1,5,true,11:54:04,false,,x"} {
+		if _, err := parseDumpRow(row); err == nil {
+			t.Errorf("%q was accepted", row)
+		}
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	dump := strings.Join([]string{
+		"seed = 42",
+		"system = duckDB",
+		"5,-1,1,false,15:16:07,false,,0,28b9c28f638f41bb",
+		"",
+		"SELECT 1",
+		"FROM G;",
+		"------",
+		"2,0.1,5,true,11:54:04", // an older entry, with the script of its graph
+		"DROP TABLE IF EXISTS G;",
+		"CREATE TABLE G(src int, trg int);",
+		"",
+		"explain analyze SELECT * FROM G;",
+		"------",
+		"5,-1,timeout,false,15:16:08,false,,0,28b9c28f638f41bb", // cut off while being written
+		"",
+		"SELECT",
+	}, "\n")
+	name := filepath.Join(t.TempDir(), "dump.txt")
+	if err := os.WriteFile(name, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seed, entries, err := readDump(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed != 42 || len(entries) != 2 {
+		t.Fatalf("got seed %v and %v entries, want seed 42 and 2 entries", seed, len(entries))
+	}
+	if e := entries[0]; e.index != 1 || e.system != "duckDB" || e.graphHash != "28b9c28f638f41bb" || e.script != nil || e.query != "SELECT 1\nFROM G;" {
+		t.Errorf("got first entry %+v", e)
+	}
+	if e := entries[1]; e.index != 2 || e.n != 2 || !reflect.DeepEqual(e.script, []string{"DROP TABLE IF EXISTS G;", "CREATE TABLE G(src int, trg int);"}) || e.query != "explain analyze SELECT * FROM G;" {
+		t.Errorf("got second entry %+v", e)
+	}
+}
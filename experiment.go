@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// An experiment, as described in a JSON spec file.
+// Fields left out of the spec take the default values of the corresponding command line flags.
+type experimentSpec struct {
+	Backends     []backendSpec                   `json:"backends"`
+	Queries      []string                        `json:"queries"`
+	Workloads    string                          `json:"workloads"`
+	Graphs       map[string]map[string]sweepSpec `json:"graphs"` // sweeps of n and p by graph family, "default" applying to every family
+	Endpoints    string                          `json:"endpoints"`
+	Order        string                          `json:"order"`
+	Repeats      int                             `json:"repeats"`
+	GraphRepeats int                             `json:"graphRepeats"`
+	Warmup       int                             `json:"warmup"`
+	Cold         bool                            `json:"cold"`
+	Load         loadTestSpec                    `json:"load"`
+	Retry        retrySpec                       `json:"retry"`
+	FailOnError  bool                            `json:"failOnError"`
+	CleanUp      bool                            `json:"cleanUp"` // delete the test graph from the database when the run is interrupted
+	Paired       bool                            `json:"paired"`  // run every query on the same graphs on every backend
+	Timeout      string                          `json:"timeout"`
+	Cutoff       int                             `json:"cutoff"`
+	Frontier     string                          `json:"frontier"` // budget of a frontier search, empty to test every size
+	Corpus       string                          `json:"corpus"`   // directory of a corpus to draw the graphs from, empty to generate them
+	Seed         int64                           `json:"seed"`
+	Output       string                          `json:"output"`
+}
+
+type loadTestSpec struct {
+	Workers  int      `json:"workers"` // 0 disables load tests
+	Duration string   `json:"duration"`
+	Count    int      `json:"count"`
+	Queries  []string `json:"queries"`
+}
+
+type retrySpec struct {
+	Attempts int      `json:"attempts"`
+	Backoff  string   `json:"backoff"`
+	On       []string `json:"on"` // error classes
+}
+
+type backendSpec struct {
+	System string `json:"system"` // neo4j, memgraph, postgres or duckDB
+	Port   int64  `json:"port"`
+	User   string `json:"user"`
+	Pwd    string `json:"pwd"`
+	DBName string `json:"dbName"`
+}
+
+func (b *backendSpec) UnmarshalJSON(data []byte) error {
+	type plainBackend backendSpec
+	backend := plainBackend{Port: 7687, User: "neo4j", Pwd: "1234"}
+	if err := decodeStrict(data, &backend); err != nil {
+		return err
+	}
+	*b = backendSpec(backend)
+	return nil
+}
+
+func loadSpec(specFile string) (experimentSpec, []byte) {
+	content, err := os.ReadFile(specFile)
+	checkErr(err)
+	spec := experimentSpec{
+		Endpoints:    string(utils.UniformEndpoints),
+		Order:        string(sequentialOrder),
+		Repeats:      5,
+		GraphRepeats: 5,
+		Warmup:       1,
+		Load:         loadTestSpec{Duration: "1m"},
+		Retry:        retrySpec{Attempts: 1, Backoff: "1s", On: []string{string(utils.ConnectivityError), string(utils.TransientError)}},
+		Timeout:      "5m",
+		Seed:         -1,
+		Output:       "results",
+	}
+	checkErr(decodeStrict(content, &spec))
+	checkErr(spec.checkGraphs())
+	return spec, content
+}
+
+// Decodes JSON data into v, rejecting the keys v has no field for, so that a misspelled option is not silently left to its default
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Checks that the sweeps of the spec are given for known graph families, and only for n and p
+func (spec experimentSpec) checkGraphs() error {
+	for family, sweeps := range spec.Graphs {
+		known := family == "default"
+		for _, f := range utils.GraphFamilies {
+			known = known || family == string(f)
+		}
+		if !known {
+			return fmt.Errorf("unknown graph family %q in graphs, expected default or one of %v", family, utils.GraphFamilies)
+		}
+		for param := range sweeps {
+			if param != "n" && param != "p" {
+				return fmt.Errorf("unknown parameter %q in the sweeps of %v, expected n or p", param, family)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the sweeps to use for graphs of the given family
+func (spec experimentSpec) sweeps(family utils.GraphFamily) map[string]sweepSpec {
+	sweeps := map[string]sweepSpec{
+		"n": linearSweep("10", "300", "10"),
+		"p": linearSweep("0.1", "1.0", "0.1"),
+	}
+	for _, graphs := range []string{"default", string(family)} {
+		for param, sweep := range spec.Graphs[graphs] {
+			sweeps[param] = sweep
+		}
+	}
+	return sweeps
+}
+
+// Runs every query of the spec given as argument on every backend of the spec,
+// and stores a copy of the spec along with the results.
+// With -resume <output directory>, resumes an interrupted experiment from the copy of its spec.
+func runExperiment(ctx context.Context, args []string) {
+	specFile := ""
+	switch {
+	case len(args) == 1:
+		specFile = args[0]
+	case len(args) == 2 && args[0] == "-resume":
+		resume = true
+		specFile = filepath.Join(args[1], "spec.json")
+	default:
+		panic(errors.New("usage : run <experiment spec.json> or run -resume <output directory>"))
+	}
+	spec, content := loadSpec(specFile)
+	if resume {
+		spec.Output = args[1]
+	}
+
+	if spec.Workloads != "" {
+		workloads, err := utils.LoadWorkloads(spec.Workloads)
+		checkErr(err)
+		checkErr(utils.RegisterQueries(workloads))
+	}
+	if len(spec.Backends) == 0 || len(spec.Queries) == 0 {
+		panic(errors.New("the experiment must have at least one backend and one query"))
+	}
+	if spec.Paired {
+		systems := make(map[string]bool)
+		for _, b := range spec.Backends {
+			if systems[b.System] {
+				panic(fmt.Errorf("%v is used twice. Each system can only be used once in a paired experiment", b.System))
+			}
+			systems[b.System] = true
+		}
+		if spec.Frontier != "" || spec.Load.Workers > 0 {
+			panic(errors.New("frontier searches and load tests cannot be paired"))
+		}
+	}
+	if spec.Corpus != "" && spec.Frontier != "" {
+		panic(errors.New("frontier searches cannot draw from a corpus"))
+	}
+	for _, b := range spec.Backends {
+		for _, name := range spec.Queries {
+			q, found := utils.FindQuery(name)
+			if !found {
+				panic(fmt.Errorf("%v is not a valid query. %v", name, utils.CatalogDescription()))
+			}
+			if !q.Supports(utils.Backend(b.System)) {
+				panic(fmt.Errorf("%v is not implemented for %v. Please change the query or the system", name, b.System))
+			}
+			if _, err := sweepCells(q.Graph(), spec.sweeps(q.Graph())); err != nil {
+				panic(fmt.Errorf("invalid sweep for %v : %w", name, err))
+			}
+		}
+		if b.System == string(utils.Postgres) && b.DBName == "" {
+			panic(errors.New("please provide the name of the postgres database to run the tests on (dbName). The database must be created before running this program"))
+		}
+	}
+
+	var err error
+	endpoints, err = utils.ParseEndpointStrategy(spec.Endpoints)
+	checkErr(err)
+	order, err = parseRunOrder(spec.Order)
+	checkErr(err)
+	timeout, err = time.ParseDuration(spec.Timeout)
+	checkErr(err)
+	repeats = spec.Repeats
+	graphRepeats = spec.GraphRepeats
+	warmup = spec.Warmup
+	cold = spec.Cold
+	cleanUp = spec.CleanUp
+	failOnError = spec.FailOnError
+	backoff, err := time.ParseDuration(spec.Retry.Backoff)
+	checkErr(err)
+	retryPolicy, err = parseRetryPolicy(spec.Retry.Attempts, backoff, spec.Retry.On)
+	checkErr(err)
+	loadWorkers = spec.Load.Workers
+	loadDuration, err = time.ParseDuration(spec.Load.Duration)
+	checkErr(err)
+	loadCount = spec.Load.Count
+	loadQueries = spec.Load.Queries
+	checkErr(checkLoadOptions())
+	checkErr(checkRepeatOptions())
+	cutoff = spec.Cutoff
+	frontier = 0
+	if spec.Frontier != "" {
+		frontier, err = time.ParseDuration(spec.Frontier)
+		checkErr(err)
+	}
+	paired = spec.Paired
+	corpus = nil
+	if spec.Corpus != "" {
+		corpus, err = openCorpus(spec.Corpus, false)
+		checkErr(err)
+		corpusRepeats := repeats
+		if loadWorkers > 0 {
+			corpusRepeats = 1
+		}
+		for _, name := range spec.Queries {
+			q, _ := utils.FindQuery(name)
+			cells, _ := sweepCells(q.Graph(), spec.sweeps(q.Graph()))
+			checkErr(corpus.covers(q.Graph(), cells, corpusRepeats))
+		}
+	}
+	outputDir = spec.Output
+	checkErr(os.MkdirAll(outputDir, 0755))
+	if !resume {
+		checkErr(os.WriteFile(filepath.Join(outputDir, "spec.json"), content, 0644))
+	}
+
+	if paired {
+		runPaired(ctx, spec)
+		return
+	}
+
+	for _, b := range spec.Backends {
+		if summary.interrupted {
+			return
+		}
+		useBackend(b)
+		connect(ctx)
+		for _, name := range spec.Queries {
+			if summary.interrupted {
+				break
+			}
+			query, _ = utils.FindQuery(name)
+			queryType = name
+			sweeps = spec.sweeps(query.Graph())
+			initRandSeed(&spec.Seed)
+			testSuite(ctx, filepath.Join(outputDir, fmt.Sprintf("%v_%v", backend, name)), nil)
+			fmt.Println()
+		}
+		shutDown()
+	}
+}
+
+// Sets the connection details of the current backend
+func useBackend(b backendSpec) {
+	backend = utils.Backend(b.System)
+	memgraph = backend == utils.Memgraph
+	postgres = backend == utils.Postgres
+	duckDB = backend == utils.DuckDB
+	boltPort = b.Port
+	username = b.User
+	pwd = b.Pwd
+	dbName = b.DBName
+}
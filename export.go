@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// The format graphs are exported to by default for each system
+var nativeExportFormats = map[utils.Backend]utils.ExportFormat{
+	utils.Neo4j:    utils.Neo4jAdminExport,
+	utils.Memgraph: utils.GraphMLExport,
+	utils.Postgres: utils.PostgresCopyExport,
+	utils.DuckDB:   utils.ParquetExport,
+}
+
+// Writes the graphs a run would load to files, for the native loaders of the systems.
+// With the same query, system, sweeps, repeats, seed and endpoints (or corpus) as a run, the graphs are those of the run,
+// so that a run with -preloaded can query a graph loaded from these files.
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	queryFlag := flags.String("query", "", "The query of the run, which decides the family of the graphs. "+utils.CatalogDescription())
+	memgraphFlag := flags.Bool("memgraph", false, "Export the graphs of a run on memgraph")
+	postgresFlag := flags.Bool("postgres", false, "Export the graphs of a run on postgres")
+	duckDBFlag := flags.Bool("duckDB", false, "Export the graphs of a run on duckDB")
+	nFlag := flags.String("n", "10:100:10", "The graph sizes. Either a list (10,20,50), a linear range (10:300:10), a geometric range (10:1000:x2) or a log scale range with a number of points (10:1000:log5)")
+	pFlag := flags.String("p", "0.1:1.0:0.1", "The edge probabilities, for the families that depend on it. Same syntax as -n")
+	repeatsFlag := flags.Int("repeats", 1, "How many graphs to export for each size and edge probability")
+	seedFlag := flags.Int64("seed", -1, "The seed of the run. Required unless the graphs are drawn from a corpus")
+	endpointsFlag := flags.String("endpoints", "uniform", fmt.Sprintf("How the start/end nodes of graphs are picked. One of %v", utils.EndpointStrategies))
+	corpusFlag := flags.String("corpus", "", "Export the graphs of the corpus in this directory instead of generating them")
+	formatFlag := flags.String("format", "", fmt.Sprintf("The formats to export to, separated by commas, among %v. Defaults to the format of the system : neo4j-admin for neo4j, postgres for postgres, parquet for duckDB and graphml for memgraph", utils.ExportFormats))
+	outputFlag := flags.String("output", "export", "The directory the graphs are written to")
+	checkErr(flags.Parse(args))
+
+	dbName := "-" // checked for postgres runs, but exports connect to no database
+	checkFlags(queryFlag, memgraphFlag, postgresFlag, duckDBFlag, &dbName)
+	formats := []utils.ExportFormat{nativeExportFormats[backend]}
+	if *formatFlag != "" {
+		formats = nil
+		for _, name := range strings.Split(*formatFlag, ",") {
+			f, err := utils.ParseExportFormat(strings.TrimSpace(name))
+			checkErr(err)
+			formats = append(formats, f)
+		}
+	}
+	var err error
+	endpoints, err = utils.ParseEndpointStrategy(*endpointsFlag)
+	checkErr(err)
+	exportSweeps := make(map[string]sweepSpec)
+	exportSweeps["n"], err = parseSweep(*nFlag)
+	checkErr(err)
+	exportSweeps["p"], err = parseSweep(*pFlag)
+	checkErr(err)
+	cells, err := sweepCells(query.Graph(), exportSweeps)
+	checkErr(err)
+	corpus = nil
+	if *corpusFlag != "" {
+		corpus, err = openCorpus(*corpusFlag, false)
+		checkErr(err)
+		checkErr(corpus.covers(query.Graph(), cells, *repeatsFlag))
+	} else if *seedFlag == -1 {
+		panic(errors.New("please give the seed of the run the graphs are exported for"))
+	}
+	seed = *seedFlag
+
+	generator, _ := query.Graph().Generator(backend)
+	for _, c := range cells {
+		for repeat := 0; repeat < *repeatsFlag; repeat++ {
+			graph := roundGraph(generator, c, repeat)
+			graphID := fmt.Sprintf("n%v-p%v-r%v", c.n, c.p, repeat) // as in paired runs
+			for _, f := range formats {
+				dir := filepath.Join(*outputFlag, graphID, string(f))
+				files, err := utils.ExportGraph(dir, graph, f)
+				checkErr(err)
+				fmt.Printf("%v : %v nodes, %v edges, %v files in %v\n", graphID, graph.Nodes, len(graph.Edges), len(files), dir)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// The largest graph size on which the query runs within the frontier budget, for one edge probability
+type frontierPoint struct {
+	p        float64
+	largest  int // 0 if even the smallest size is over budget
+	smallest int // the smallest size found over budget, 0 if every size probed is within budget
+}
+
+// Searches the frontier of the current query for each edge probability of the p sweep, and writes it to filePrefix_frontier.csv.
+// Sizes grow exponentially from the smallest size of the n sweep until a probe goes over budget or the largest size is reached,
+// then a binary search narrows the frontier down between the last size within budget and the first one over it.
+// Every query run is also written to the result and dump files, as in a grid sweep.
+func frontierSearch(ctx context.Context, filePrefix string, resultFile *os.File, dumpFile *os.File) {
+	frontierFile, err := os.Create(filePrefix + "_frontier.csv")
+	checkErr(err)
+	defer frontierFile.Close()
+	_, err = frontierFile.WriteString("system,query,edge probability,largest size,smallest size over budget,budget\n")
+	checkErr(err)
+
+	sizes, err := sweeps["n"].sizes()
+	checkErr(err)
+	probabilities, err := sweepProbabilities(query.Graph(), sweeps)
+	checkErr(err)
+	minSize, maxSize := sizes[0], sizes[0]
+	for _, n := range sizes {
+		minSize = min(minSize, n)
+		maxSize = max(maxSize, n)
+	}
+
+	for _, p := range probabilities {
+		point := searchFrontier(ctx, p, minSize, maxSize, resultFile, dumpFile)
+		if ctx.Err() != nil { // The frontier of p is incomplete
+			summary.interrupted = true
+			return
+		}
+		_, err = frontierFile.WriteString(fmt.Sprintf("%v,%v,%v,%v,%v,%v\n", backend, query.Name(), p, point.largest, point.smallest, frontier.Milliseconds()))
+		checkErr(err)
+	}
+}
+
+func searchFrontier(ctx context.Context, p float64, minSize int, maxSize int, resultFile *os.File, dumpFile *os.File) frontierPoint {
+	point := frontierPoint{p: p}
+	for n := minSize; ; n = min(2*n, maxSize) {
+		if !probe(ctx, n, p, resultFile, dumpFile) {
+			point.smallest = n
+			break
+		}
+		point.largest = n
+		if n == maxSize {
+			return point
+		}
+	}
+	for lo, hi := max(point.largest, minSize-1), point.smallest; hi-lo > 1; {
+		mid := (lo + hi) / 2
+		if probe(ctx, mid, p, resultFile, dumpFile) {
+			lo, point.largest = mid, mid
+		} else {
+			hi, point.smallest = mid, mid
+		}
+	}
+	return point
+}
+
+// Runs the query on repeats graphs of size n, and returns whether the median execution time is within budget.
+// Queries that time out or fail, and graphs that cannot be loaded, count as over budget, and an interrupted probe fails.
+func probe(ctx context.Context, n int, p float64, resultFile *os.File, dumpFile *os.File) bool {
+	generator, _ := query.Graph().Generator(backend)
+	times := make([]time.Duration, 0)
+	for reps := 0; reps < repeats; reps++ {
+		if ctx.Err() != nil {
+			return false
+		}
+		c := cell{n: n, p: p}
+		utils.SetSeed(roundSeed(c, reps, -1))
+		graph := generator(n, p, endpoints)
+		createGraphQuery := utils.GraphScript(backend, graph)
+		loadRetries, err := loadGraph(ctx, createGraphQuery, n)
+		if err != nil {
+			failRound(c, reps, graph, err, loadRetries, resultFile)
+			times = append(times, frontier+1)
+			continue
+		}
+		for _, qRes := range testRound(ctx, c, reps, graph, createGraphQuery, loadRetries, resultFile, dumpFile, newCutoffPolicy(0), nil) {
+			if qRes.QExecTime < 0 {
+				times = append(times, frontier+1)
+			} else {
+				times = append(times, time.Duration(qRes.QExecTime)*time.Millisecond)
+			}
+		}
+	}
+	if len(times) == 0 {
+		return false
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2] <= frontier
+}
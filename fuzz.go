@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// The queries the fuzzer can check : those that run on duckDB and have a reference solver
+func fuzzQueries() []utils.Query {
+	queries := make([]utils.Query, 0)
+	for _, q := range utils.Catalog {
+		if _, hasOracle := utils.FindOracle(q.Name()); hasOracle && q.Supports(utils.DuckDB) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// What duckDB and the reference solver answered on an instance
+type fuzzOutcome struct {
+	result utils.QueryResult
+	oracle bool
+	solved bool // false if the instance was too large for the solver
+}
+
+// Whether duckDB failed, or answered differently from the reference solver
+func (o fuzzOutcome) disagrees() bool {
+	if o.result.QExecTime == -5 {
+		return true
+	}
+	return o.result.QExecTime >= 0 && o.solved && o.result.Found != o.oracle
+}
+
+// Whether o disagrees the same way as other : duckDB fails in both, or gives the same wrong answer
+func (o fuzzOutcome) disagreesLike(other fuzzOutcome) bool {
+	if !o.disagrees() {
+		return false
+	}
+	if o.result.QExecTime == -5 || other.result.QExecTime == -5 {
+		return o.result.QExecTime == other.result.QExecTime
+	}
+	return o.result.Found == other.result.Found
+}
+
+func (o fuzzOutcome) String() string {
+	oracle := "none"
+	if o.solved {
+		oracle = fmt.Sprint(o.oracle)
+	}
+	answer := outcome(o.result)
+	if o.result.QExecTime >= 0 {
+		answer = fmt.Sprint(o.result.Found)
+	} else if o.result.Err != nil {
+		answer += " (" + strings.Join(strings.Fields(o.result.Err.Error()), " ") + ")"
+	}
+	return fmt.Sprintf("duckDB=%v oracle=%v", answer, oracle)
+}
+
+// Opens an in-memory duckDB database for the fuzzer, leaving the database file of the benchmarks alone
+func openFuzzDB() {
+	newDB, err := sql.Open("duckdb", "")
+	checkErr(err)
+	db = newDB
+	backend = utils.DuckDB
+	duckDB = true
+	memgraph, postgres = false, false
+}
+
+// Returns the seed of the instance-th instance of a fuzzing run
+func fuzzSeed(instance int) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "fuzz/%v/%v", seed, instance)
+	return int64(h.Sum64())
+}
+
+// Generates a graph of 2 to maxNodes nodes for q from instanceSeed, along with the nodes of the query
+func fuzzInstance(q utils.Query, instanceSeed int64, maxNodes int) (*utils.Graph, utils.QueryParams) {
+	utils.SetSeed(instanceSeed)
+	n := 2 + utils.RandomIndex(maxNodes-1)
+	p := float64(1+utils.RandomIndex(9)) / 10
+	generator, _ := q.Graph().Generator(utils.DuckDB)
+	g := generator(n, p, utils.UniformEndpoints)
+	return g, utils.QueryParams(utils.UniformEndpoints.Pick(g, len(q.Parameters())/2))
+}
+
+// Loads g in duckDB and runs q on it with the given nodes, then solves the same instance in Go
+func fuzzCheck(ctx context.Context, q utils.Query, g *utils.Graph, nodes utils.QueryParams) fuzzOutcome {
+	var o fuzzOutcome
+	o.oracle, o.solved = fuzzOracle(q)(g, nodes)
+	queryString, err := q.RenderNodes(utils.DuckDB, g, nodes)
+	if err == nil {
+		err = utils.SetUpDB(ctx, db, utils.GraphScript(utils.DuckDB, g), g.Nodes)
+	}
+	if err != nil {
+		o.result = utils.QueryResult{QExecTime: -5, Err: err}
+		return o
+	}
+	o.result = runQuery(ctx, q, queryString)
+	return o
+}
+
+func fuzzOracle(q utils.Query) utils.Oracle {
+	oracle, _ := utils.FindOracle(q.Name())
+	return oracle
+}
+
+// Runs the queries of the fuzzer on many small random graphs, on duckDB and with their reference solver,
+// stopping at the first instance on which they disagree and writing a shrunk reproducer of it
+func fuzz(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	iterationsFlag := flags.Int("iterations", 1000, "How many instances to check. 0 checks instances until interrupted")
+	maxNodesFlag := flags.Int("maxNodes", 8, "How big the largest graph should be")
+	queriesFlag := flags.String("queries", "", "The queries to check, separated by commas. All the queries that run on duckDB and have a reference solver by default")
+	seedFlag := flags.Int64("seed", -1, "A seed for the rng. Will be generated using current time if ommited")
+	timeoutFlag := flags.Duration("timeout", 10*time.Second, "How long a query may run before the instance is left out")
+	outputFlag := flags.String("output", "results", "The directory reproducers are written to")
+	checkErr(flags.Parse(args))
+	if *maxNodesFlag < 2 {
+		panic(fmt.Errorf("maxNodes must be at least 2"))
+	}
+
+	queries := fuzzQueries()
+	if *queriesFlag != "" {
+		queries = make([]utils.Query, 0)
+		for _, name := range strings.Split(*queriesFlag, ",") {
+			q, found := utils.FindQuery(name)
+			if !found {
+				panic(fmt.Errorf("%v is not a valid query. %v", name, utils.CatalogDescription()))
+			}
+			if _, hasOracle := utils.FindOracle(name); !hasOracle || !q.Supports(utils.DuckDB) {
+				panic(fmt.Errorf("%v cannot be fuzzed : it must run on duckDB and have a reference solver", name))
+			}
+			queries = append(queries, q)
+		}
+	}
+	initRandSeed(seedFlag)
+	timeout = *timeoutFlag
+	outputDir = *outputFlag
+	openFuzzDB()
+	defer closeDB(context.Background())
+
+	timeouts := 0
+	for i := 0; *iterationsFlag == 0 || i < *iterationsFlag; i++ {
+		if ctx.Err() != nil {
+			summary.interrupted = true
+			break
+		}
+		q := queries[i%len(queries)]
+		instanceSeed := fuzzSeed(i)
+		g, nodes := fuzzInstance(q, instanceSeed, *maxNodesFlag)
+		o := fuzzCheck(ctx, q, g, nodes)
+		if o.result.QExecTime == -1 {
+			timeouts++
+		}
+		if o.result.QExecTime == -4 {
+			summary.interrupted = true
+			break
+		}
+		fmt.Printf("\r[%v]Checked %v instances (%v timeouts)", time.Now().Format("2006-01-02T15:04:05"), i+1, timeouts)
+		if o.disagrees() {
+			fmt.Printf("\n%v disagrees on instance %v (seed %v) : %v. Shrinking it...\n", q.Name(), i+1, instanceSeed, o)
+			reproducer := writeFuzzReproducer(ctx, q, instanceSeed, g, nodes, o)
+			fmt.Printf("Reproducer written to %v\n", reproducer)
+			closeDB(context.Background())
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("\nNo disagreement found (seed %v)\n", seed)
+}
+
+// Shrinks an instance on which duckDB and the reference solver disagree, and writes it to the output directory
+func writeFuzzReproducer(ctx context.Context, q utils.Query, instanceSeed int64, g *utils.Graph, nodes utils.QueryParams, o fuzzOutcome) string {
+	ctx = context.WithoutCancel(ctx)
+	shrunk, shrunkNodes := utils.Minimize(g, nodes, func(candidate *utils.Graph, candidateNodes utils.QueryParams) bool {
+		return fuzzCheck(ctx, q, candidate, candidateNodes).disagreesLike(o)
+	})
+	shrunkOutcome := fuzzCheck(ctx, q, shrunk, shrunkNodes)
+	queryString, _ := q.RenderNodes(utils.DuckDB, shrunk, shrunkNodes)
+
+	content := fmt.Sprintf("%v on a %v graph (seed %v) : %v\n", q.Name(), g.Family, instanceSeed, o)
+	content += fmt.Sprintf("shrunk from %v nodes and %v edges to %v nodes and %v edges : %v\n", g.Nodes, len(g.Edges), shrunk.Nodes, len(shrunk.Edges), shrunkOutcome)
+	if len(shrunkNodes) > 0 {
+		content += fmt.Sprintf("nodes %v\n", shrunkNodes)
+	}
+	content += fmt.Sprintf("\n== duckDB ==\n%v\n\n%v\n", strings.Join(utils.GraphScript(utils.DuckDB, shrunk), "\n"), queryString)
+
+	checkErr(os.MkdirAll(outputDir, 0755))
+	reproducer := filepath.Join(outputDir, fmt.Sprintf("fuzz_%v_%v.txt", q.Name(), uint64(instanceSeed)))
+	checkErr(os.WriteFile(reproducer, []byte(content), 0644))
+	return reproducer
+}
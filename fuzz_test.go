@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The instances of the seed corpus of each fuzzed query, small enough for duckDB to answer them within the timeout of the test,
+// with both yes and no answers
+var fuzzSeedInstances = map[string][]int64{
+	"hamil":       {1, 3, 7},
+	"euler":       {3, 5, 10},
+	"SubsetSum":   {1, 2, 11},
+	"AStarBAStar": {3, 6, 10},
+}
+
+// Checks the queries of the fuzzer on duckDB against their reference solver, on graphs of at most 8 nodes.
+// Run it with go test -fuzz FuzzDuckDB ; without -fuzz, only the seed corpus is checked.
+// Instances of the seed corpus fail when they time out, and the instances generated by the fuzzer are skipped.
+func FuzzDuckDB(f *testing.F) {
+	queries := fuzzQueries()
+	for i, q := range queries {
+		instances, found := fuzzSeedInstances[q.Name()]
+		if !found {
+			f.Fatalf("%v has no seed instances", q.Name())
+		}
+		for _, instanceSeed := range instances {
+			f.Add(uint8(i), instanceSeed)
+		}
+	}
+	timeout = 2 * time.Second
+	openFuzzDB()
+	f.Cleanup(func() { closeDB(context.Background()) })
+
+	f.Fuzz(func(t *testing.T, query uint8, instanceSeed int64) {
+		q := queries[int(query)%len(queries)]
+		g, nodes := fuzzInstance(q, instanceSeed, 8)
+		o := fuzzCheck(context.Background(), q, g, nodes)
+		if o.disagrees() {
+			t.Fatalf("%v disagrees on a %v graph of %v nodes (seed %v) : %v", q.Name(), g.Family, g.Nodes, instanceSeed, o)
+		}
+		if o.result.QExecTime == -1 {
+			for _, seedInstance := range fuzzSeedInstances[q.Name()] {
+				if instanceSeed == seedInstance {
+					t.Fatalf("%v timed out on seed instance %v, a graph of %v nodes and %v edges", q.Name(), instanceSeed, g.Nodes, len(g.Edges))
+				}
+			}
+			t.Skipf("%v timed out on a graph of %v nodes and %v edges (seed %v), nothing is checked", q.Name(), g.Nodes, len(g.Edges), instanceSeed)
+		}
+		if !o.solved {
+			t.Skipf("the reference solver of %v cannot solve a graph of %v nodes (seed %v), nothing is checked", q.Name(), g.Nodes, instanceSeed)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// One query run of a load test
+type loadRun struct {
+	query   utils.Query
+	worker  int
+	start   time.Duration // since the start of the load test
+	latency time.Duration // as seen by the worker, waiting for a connection included
+	result  utils.QueryResult
+	params  utils.QueryParams
+}
+
+// Guards the random choices of the workers, as the random number generator of utils is not safe for concurrent use
+var renderMu sync.Mutex
+
+// Returns the queries the workers of a load test pick from : the current query and the ones of -loadQueries,
+// which must run on the same graph family
+func loadMix() ([]utils.Query, error) {
+	mix := []utils.Query{query}
+	for _, name := range loadQueries {
+		if name == query.Name() {
+			continue
+		}
+		q, found := utils.FindQuery(name)
+		if !found {
+			return nil, fmt.Errorf("%v is not a valid query. %v", name, utils.CatalogDescription())
+		}
+		if q.Graph() != query.Graph() {
+			return nil, fmt.Errorf("%v runs on %v graphs, but %v runs on %v graphs", name, q.Graph(), query.Name(), query.Graph())
+		}
+		if !q.Supports(backend) {
+			return nil, fmt.Errorf("%v is not implemented for %v", name, backend)
+		}
+		mix = append(mix, q)
+	}
+	return mix, nil
+}
+
+// Runs a load test on one graph of each configuration of the n and p sweeps :
+// loadWorkers workers run queries of the mix concurrently, for loadDuration or until loadCount queries were run.
+// Every query run is written to filePrefix_load_runs.csv, and the throughput, latency percentiles and outcomes
+// of each configuration, by query and for the whole mix, to filePrefix_load.csv.
+func loadTest(ctx context.Context, filePrefix string) {
+	mix, err := loadMix()
+	checkErr(err)
+	runsFile, err := os.Create(filePrefix + "_load_runs.csv")
+	checkErr(err)
+	defer runsFile.Close()
+	_, err = runsFile.WriteString("query,worker,order,edge probability,start,latency,query execution time,found,params\n")
+	checkErr(err)
+	statsFile, err := os.Create(filePrefix + "_load.csv")
+	checkErr(err)
+	defer statsFile.Close()
+	_, err = statsFile.WriteString("system,query,order,edge probability,workers,runs,seconds,throughput,p50,p95,p99,max,ok,timeout,outOfMemory,error,interrupted\n")
+	checkErr(err)
+
+	_, err = retryPolicy.Do(ctx, func() error {
+		return utils.CleanUpDB(ctx, db, -1)
+	})
+	checkErr(err)
+	generator, _ := query.Graph().Generator(backend)
+	cells, err := sweepCells(query.Graph(), sweeps)
+	checkErr(err)
+	checkErr(corpus.covers(query.Graph(), cells, 1))
+	for _, c := range cells {
+		if ctx.Err() != nil {
+			summary.interrupted = true
+			return
+		}
+		fmt.Printf("\r[%v]Load test : p=%v, n=%v, %v workers", time.Now().Format("2006-01-02T15:04:05"), c.p, c.n, loadWorkers)
+		graph := roundGraph(generator, c, 0)
+		createGraphQuery := utils.GraphScript(backend, utils.PairedView(graph, backend))
+		if _, err := loadGraph(ctx, createGraphQuery, c.n); err != nil {
+			recordFailure(c, 0, 0, fmt.Errorf("loading the graph : %w", err))
+			continue
+		}
+
+		runs, elapsed := runLoad(ctx, graph, mix)
+		if ctx.Err() != nil { // The load test of c is incomplete
+			summary.interrupted = true
+			summary.interruptedAt = &c
+			return
+		}
+		for _, r := range runs {
+			params := make([]string, 0)
+			for _, param := range r.params {
+				params = append(params, fmt.Sprint(param))
+			}
+			_, err = runsFile.WriteString(fmt.Sprintf("%v,%v,%v,%v,%v,%v,%v,%v,%v\n", r.query.Name(), r.worker, c.n, c.p, r.start.Milliseconds(), r.latency.Milliseconds(), outcome(r.result), r.result.Found, strings.Join(params, " ")))
+			checkErr(err)
+			summary.queries++
+			if r.result.QExecTime == -1 {
+				summary.timeouts++
+			}
+		}
+		for _, q := range append([]utils.Query{nil}, mix...) {
+			_, err = statsFile.WriteString(loadStats(runs, q, c, elapsed) + "\n")
+			checkErr(err)
+		}
+		summary.rounds++
+	}
+}
+
+// Runs the workers of a load test on graph, and returns every query run and how long the test took
+func runLoad(ctx context.Context, graph *utils.Graph, mix []utils.Query) ([]loadRun, time.Duration) {
+	issuing, stopIssuing := context.WithCancel(ctx)
+	defer stopIssuing()
+	if loadCount == 0 {
+		issuing, stopIssuing = context.WithTimeout(ctx, loadDuration)
+		defer stopIssuing()
+	}
+
+	start := time.Now()
+	var issued atomic.Int64
+	runs := make(chan loadRun)
+	var workers sync.WaitGroup
+	for w := 0; w < loadWorkers; w++ {
+		workers.Add(1)
+		go func(worker int) {
+			defer workers.Done()
+			// Queries started before the end of the test run to completion, so only issuing new ones is stopped
+			for issuing.Err() == nil && (loadCount == 0 || issued.Add(1) <= int64(loadCount)) {
+				renderMu.Lock()
+				q := mix[utils.RandomIndex(len(mix))]
+				queryString, params, err := q.Render(backend, graph, endpoints)
+				renderMu.Unlock()
+				run := loadRun{query: q, worker: worker, start: time.Since(start), params: params}
+				if err != nil {
+					run.result = utils.QueryResult{QExecTime: -5, Found: false, Err: err}
+				} else {
+					run.result = runQuery(ctx, q, queryString)
+				}
+				run.latency = time.Since(start) - run.start
+				runs <- run
+			}
+		}(w)
+	}
+	go func() {
+		workers.Wait()
+		close(runs)
+	}()
+
+	results := make([]loadRun, 0)
+	for r := range runs {
+		results = append(results, r)
+	}
+	return results, time.Since(start)
+}
+
+// Returns the line of filePrefix_load.csv for the runs of q, or of every query if q is nil
+func loadStats(runs []loadRun, q utils.Query, c cell, elapsed time.Duration) string {
+	name := "all"
+	if q != nil {
+		name = q.Name()
+	}
+	latencies := make([]time.Duration, 0)
+	outcomes := make(map[string]int)
+	total := 0
+	for _, r := range runs {
+		if q != nil && r.query.Name() != q.Name() {
+			continue
+		}
+		total++
+		o := outcome(r.result)
+		if r.result.QExecTime >= 0 {
+			o = "ok"
+			latencies = append(latencies, r.latency)
+		}
+		outcomes[o]++
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	throughput := float64(outcomes["ok"]) / elapsed.Seconds()
+	return fmt.Sprintf("%v,%v,%v,%v,%v,%v,%.3f,%.3f,%v,%v,%v,%v,%v,%v,%v,%v,%v", backend, name, c.n, c.p, loadWorkers, total, elapsed.Seconds(), throughput,
+		percentile(latencies, 0.5), percentile(latencies, 0.95), percentile(latencies, 0.99), percentile(latencies, 1),
+		outcomes["ok"], outcomes["timeout"], outcomes["outOfMemory"], outcomes["error"], outcomes["interrupted"])
+}
+
+// Returns the q-th quantile of sorted latencies in milliseconds (nearest rank), or nothing if there are none
+func percentile(sorted []time.Duration, q float64) string {
+	if len(sorted) == 0 {
+		return ""
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return fmt.Sprint(sorted[max(rank, 0)].Milliseconds())
+}
+
+// Returns the execution time of a query run as written to the results, or its outcome if it did not complete
+func outcome(res utils.QueryResult) string {
+	switch res.QExecTime {
+	case -1:
+		return "timeout"
+	case -2:
+		return "outOfMemory"
+	case -3:
+		return "skipped"
+	case -4:
+		return "interrupted"
+	case -5:
+		return "error"
+	default:
+		return fmt.Sprint(res.QExecTime)
+	}
+}
+
+func checkLoadOptions() error {
+	if loadWorkers < 0 || loadCount < 0 {
+		return errors.New("the number of workers and of queries of a load test cannot be negative")
+	}
+	if loadWorkers > 0 && loadCount == 0 && loadDuration <= 0 {
+		return errors.New("a load test needs a duration or a number of queries")
+	}
+	return nil
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Arogova/neo4j_performance_test/utils"
@@ -19,9 +22,90 @@ import (
 )
 
 func main() {
+	// On SIGINT or SIGTERM, ctx is canceled : the running query is stopped and the run ends after writing what completed.
+	// A second signal kills the program.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		runExperiment(ctx, os.Args[2:])
+		finish(fmt.Sprintf("run -resume %v", outputDir))
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fuzz" {
+		fuzz(ctx, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "minimize" {
+		minimize(ctx, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(ctx, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "corpus" {
+		corpusCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		export(os.Args[2:])
+		return
+	}
+
 	setUpFlags()
+
+	connect(ctx)
+
+	filePrefix := fmt.Sprintf("%v/%v_%v", outputDir, queryType, time.Now().Format("2006-01-02--15:04:05"))
+	if resume {
+		filePrefix = resumePrefix
+	}
+	testSuite(ctx, filePrefix, os.Args[1:])
+	shutDown()
+	finish(fmt.Sprintf("-resume %v", filePrefix))
+}
+
+// Cleans up the database if the run was interrupted and -cleanUp is set, then closes the connection
+func shutDown() {
 	ctx := context.Background()
+	if summary.interrupted && cleanUp {
+		checkErr(utils.CleanUpDB(ctx, db, -1))
+	}
+	closeDB(ctx)
+}
+
+// Prints the query runs that failed, then, if the run was interrupted, what completed and how to resume the run.
+// Exits with code 130 if the run was interrupted, or 1 if a query run failed and -failOnError is set.
+func finish(resumeOption string) {
+	if len(summary.failures) > 0 {
+		fmt.Printf("\n%v query runs failed :\n", len(summary.failures))
+		for _, f := range summary.failures {
+			fmt.Printf("  %v : p=%v, n=%v, repeat %v, iteration %v : %v\n", f.filePrefix, f.c.p, f.c.n, f.repeat, f.iteration+1, strings.Join(strings.Fields(f.err.Error()), " "))
+		}
+	}
+	if len(summary.disagreements) > 0 {
+		fmt.Printf("\n%v instances were answered differently, see the reproducers in %v\n", len(summary.disagreements), filepath.Dir(summary.disagreements[0]))
+	}
+	if !summary.interrupted {
+		if len(summary.failures) > 0 && failOnError {
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Printf("\nInterrupted. %v query runs completed (%v timeouts) on %v graphs", summary.queries, summary.timeouts, summary.rounds)
+	if summary.interruptedAt != nil {
+		fmt.Printf(", stopped at p=%v, n=%v", summary.interruptedAt.p, summary.interruptedAt.n)
+	}
+	fmt.Printf(".\nResults are in %v.csv, resume with : go run . %v\n", summary.filePrefix, resumeOption)
+	os.Exit(130)
+}
 
+func connect(ctx context.Context) {
 	if postgres {
 		connectToPostgres()
 	} else if duckDB {
@@ -29,19 +113,19 @@ func main() {
 	} else {
 		connectToNeo4j(ctx)
 	}
+}
 
+func closeDB(ctx context.Context) {
 	switch db.(type) {
 	case neo4j.DriverWithContext:
-		defer utils.HandleClose(ctx, db.(neo4j.DriverWithContext))
+		checkErr(db.(neo4j.DriverWithContext).Close(ctx))
 	case *pgxpool.Pool:
-		defer db.(*pgxpool.Pool).Close()
+		db.(*pgxpool.Pool).Close()
 	case *sql.DB:
-		defer db.(*sql.DB).Close()
+		db.(*sql.DB).Close()
 	default:
-		panic(errors.New("Defer close : Database type unknown. This should not happen!"))
+		panic(errors.New("Close : Database type unknown. This should not happen!"))
 	}
-
-	testSuite(ctx)
 }
 
 func connectToNeo4j(ctx context.Context) {
@@ -59,136 +143,348 @@ func connectToNeo4j(ctx context.Context) {
 func connectToPostgres() {
 	poolConfig, err := pgxpool.ParseConfig(fmt.Sprintf("postgres://%v:%v@localhost:5432/%v?sslmode=prefer", username, pwd, dbName))
 	checkErr(err)
-	poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = "5min"
+	poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
 	newDB, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 
 	db = newDB
 }
 
+// Opens the duckDB database file, trying again as set by the retry policy while another process holds its lock
 func connectToDuckDB() {
-	var err error
-
 	dbFile := "graph_query_tests.duckdb"
-	db, err = sql.Open("duckdb", dbFile)
+	_, err := retryPolicy.Do(context.Background(), func() error {
+		var err error
+		db, err = sql.Open("duckdb", dbFile)
+		return err
+	})
 	checkErr(err)
 }
 
-func testSuite(ctx context.Context) {
-	resultFile, dumpFile := createFiles(queryType)
+// Runs every configuration of the current query, writing results to filePrefix.csv and filePrefix_dump.txt.
+// Progress is journaled to filePrefix_checkpoint.jsonl, and when resuming, the configurations it lists as completed are not run again.
+// args are the command line options of the run, stored in the journal.
+func testSuite(ctx context.Context, filePrefix string, args []string) {
+	resuming := resume && fileExists(checkpointFile(filePrefix))
+	summary.filePrefix = filePrefix
+	if loadWorkers > 0 {
+		if resuming {
+			panic(errors.New("resuming a load test is not supported"))
+		}
+		loadTest(ctx, filePrefix)
+		return
+	}
+	var progress *checkpoint
+	if resuming {
+		if frontier > 0 {
+			panic(errors.New("resuming a frontier search is not supported"))
+		}
+		progress = resumeCheckpoint(filePrefix)
+	} else if frontier == 0 {
+		progress = createCheckpoint(filePrefix, args)
+	}
+	resultFile, dumpFile := createFiles(filePrefix, resuming)
 	defer resultFile.Close()
 	defer dumpFile.Close()
 
-	utils.CleanUpDB(ctx, db, -1)
-	
-	if duckDB {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(time.Minute*5)) 
-		defer cancel()
-	}
-
-	if doubleLine {
-		for n := minNodes; n <= maxNodes; n += inc {
-			for reps := 0; reps < repeats; reps++ {
-				var createGraphQuery []string
-				if postgres || duckDB {
-					createGraphQuery = utils.CreateRandomDoubleLineGraphScriptSQL(n)
-				} else {
-					createGraphQuery = utils.CreateRandomDoubleLineGraphScript(n)
-				}
-				utils.SetUpDB(ctx, db, createGraphQuery, n)
-				testRound(ctx, n, -1.0, createGraphQuery, resultFile, dumpFile)
-			}
+	if !preloaded {
+		_, err := retryPolicy.Do(ctx, func() error {
+			return utils.CleanUpDB(ctx, db, -1)
+		})
+		checkErr(err)
+	}
+
+	if frontier > 0 {
+		frontierSearch(ctx, filePrefix, resultFile, dumpFile)
+		return
+	}
+	defer progress.Close()
+
+	generator, _ := query.Graph().Generator(backend)
+	cells, err := sweepCells(query.Graph(), sweeps)
+	checkErr(err)
+	checkErr(corpus.covers(query.Graph(), cells, repeats))
+	if preloaded && (len(cells) != 1 || repeats != 1) {
+		panic(fmt.Errorf("a preloaded run tests the graph loaded in the database only, but the options test %v graphs", len(cells)*repeats))
+	}
+	rounds := order.rounds(cells)
+	policy := newCutoffPolicy(cutoff)
+	progress.replay(rounds, policy)
+	if _, hasOracle := utils.FindOracle(query.Name()); hasOracle {
+		disagreements = newDisagreementDetector(filePrefix, []utils.Backend{backend}, resuming)
+		defer func() {
+			disagreements.Close()
+			disagreements = nil
+		}()
+	}
+	for _, r := range rounds {
+		if ctx.Err() != nil {
+			summary.interrupted = true
+			return
 		}
-	} else {
-		for p := start_p; p <= end_p; p += 0.1 {
-			for n := minNodes; n <= maxNodes; n += inc {
-				for reps := 0; reps < repeats; reps++ {
-					var createGraphQuery []string
-					if edgeValue {
-						createGraphQuery = utils.CreateEdgeValueGraphScript(n, p)
-					} else if nodeValue {
-						createGraphQuery = utils.CreateNodeValueGraphScript(n, p)
-					} else if labeled && !(postgres || duckDB){
-						createGraphQuery = utils.CreateLabeledGraphScript(n, p)
-					} else if labeled && postgres {
-						createGraphQuery = utils.CreateLabeledGraphScriptSQL(n, p)
-					} else if labeled && duckDB {
-						createGraphQuery = utils.CreateLabeledGraphScriptDuckDB(n,p)
-					} else if postgres || duckDB {
-						createGraphQuery = utils.CreateRandomGraphScriptSQL(n, p)
-					} else {
-						createGraphQuery = utils.CreateRandomGraphScript(n, p)
-					}
-					utils.SetUpDB(ctx, db, createGraphQuery, n)
-					testRound(ctx, n, p, createGraphQuery, resultFile, dumpFile)
-				}
-			}
+		if progress.roundCompleted(r.c, r.repeat) {
+			continue
+		}
+		if policy.dominated(r.c) {
+			skipRound(r.c, r.repeat, resultFile, progress)
+			continue
+		}
+		graph := roundGraph(generator, r.c, r.repeat)
+		createGraphQuery := utils.GraphScript(backend, utils.PairedView(graph, backend)) // corpus graphs are loaded as in paired runs
+		loadRetries := 0
+		if !preloaded {
+			loadRetries, err = loadGraph(ctx, createGraphQuery, r.c.n)
+		}
+		if err != nil {
+			failRound(r.c, r.repeat, graph, err, loadRetries, resultFile)
+			continue
+		}
+		testRound(ctx, r.c, r.repeat, graph, createGraphQuery, loadRetries, resultFile, dumpFile, policy, progress)
+		disagreements.check(r.c, r.repeat, graph)
+		if !summary.interrupted {
+			summary.rounds++
 		}
 	}
 }
 
-func testRound(ctx context.Context, n int, p float64, createGraphQuery []string, resultFile *os.File, dumpFile *os.File) {
-	var ignore bool
+// Records the queries of a round as skipped, without running them
+func skipRound(c cell, repeat int, resultFile *os.File, progress *checkpoint) {
+	for i := 0; i < graphRepeats; i++ {
+		skipped := testResult{nodes: c.n, probability: c.p, queryResult: utils.QueryResult{QExecTime: -3, Found: false}, warmup: i < warmup, graphID: pairedGraphID(c, repeat)}
+		writeToFile(resultFile, &skipped, false)
+		progress.record(iterationRecord{N: c.n, P: c.p, Repeat: repeat, Iteration: i, Skipped: true})
+	}
+}
+
+// Loads a graph of n nodes in the database, trying again as set by the retry policy. Returns the number of retries.
+// A graph being loaded is loaded completely even if ctx is canceled.
+func loadGraph(ctx context.Context, createGraphQuery []string, n int) (int, error) {
+	return retryPolicy.Do(ctx, func() error {
+		return utils.SetUpDB(context.WithoutCancel(ctx), db, createGraphQuery, n)
+	})
+}
+
+// Records the queries of a round whose graph could not be loaded as failed, the graph being stored all the same.
+// They are not journaled, so that a resumed run tries again.
+func failRound(c cell, repeat int, graph *utils.Graph, err error, retries int, resultFile *os.File) {
+	graphHash := storeGraph(graph)
+	for i := 0; i < graphRepeats; i++ {
+		failed := testResult{nodes: c.n, probability: c.p, queryResult: utils.QueryResult{QExecTime: -5, Found: false, Err: fmt.Errorf("loading the graph : %w", err)}, warmup: i < warmup, retries: retries, graphID: pairedGraphID(c, repeat), graphHash: graphHash}
+		writeToFile(resultFile, &failed, false)
+		recordFailure(c, repeat, i, failed.queryResult.Err)
+	}
+}
+
+func recordFailure(c cell, repeat int, iteration int, err error) {
+	summary.failures = append(summary.failures, failure{filePrefix: summary.filePrefix, c: c, repeat: repeat, iteration: iteration, err: err})
+}
+
+// Runs the query graphRepeats times on graph, the first warmup runs being warm-up runs, and returns the results of the measured runs.
+// Measured runs already journaled in progress are not run again, but warm-up runs always are.
+// If ctx is canceled, the running query is recorded as interrupted and the round stops.
+// Query runs that fail are recorded with their error and returned with the others, and the round goes on.
+// Results count the retries of their query run and of the load of graph (loadRetries).
+func testRound(ctx context.Context, c cell, repeat int, graph *utils.Graph, createGraphQuery []string, loadRetries int, resultFile *os.File, dumpFile *os.File, policy *cutoffPolicy, progress *checkpoint) []utils.QueryResult {
+	n, p := c.n, c.p
+	results := make([]utils.QueryResult, 0)
+	graphHash := storeGraph(graph)
 	for i := 0; i < graphRepeats; i++ {
-		if i == 0 {
-			ignore = true
+		key := roundKey{n: n, p: p, repeat: repeat, iteration: i}
+		isWarmup := i < warmup
+		if !isWarmup && progress.completed(key) {
+			continue
+		}
+		if ctx.Err() != nil {
+			summary.interrupted = true
+			return results
 		}
 		fmt.Printf("\r[%v]Currently computing : p=%v, n=%v (iteration %v)", time.Now().Format("2006-01-02T15:04:05"), p, n, i+1)
-		c := make(chan utils.QueryResult)
-		query := createRandomQuery(n)
-
-		go utils.ExecuteQuery(ctx, db, query, c, memgraph)
-		qRes := <-c
-		if !(ignore) {
-			formattedRes, formattedDump := formatTestResult(qRes, n, p, createGraphQuery, query)
-			writeToFile(resultFile, &formattedRes, false)
-			writeToFile(dumpFile, &formattedDump, true)
+		utils.SetSeed(roundSeed(c, repeat, i))
+		queryString, params, err := query.Render(backend, graph, endpoints)
+		if err == nil && cold && !isWarmup {
+			err = clearCaches(context.WithoutCancel(ctx))
+		}
+		var qRes utils.QueryResult
+		queryRetries := 0
+		if err != nil {
+			qRes = utils.QueryResult{QExecTime: -5, Found: false, Err: err}
+		} else {
+			queryRetries, _ = retryPolicy.Do(ctx, func() error {
+				qRes = runQuery(ctx, query, queryString)
+				return qRes.Err
+			})
+		}
+		if progress.completed(key) {
+			continue
+		}
+		formattedRes, formattedDump := formatTestResult(qRes, n, p, params, graphHash, queryString, isWarmup)
+		formattedRes.retries = loadRetries + queryRetries
+		formattedDump.retries = formattedRes.retries
+		formattedRes.graphID = pairedGraphID(c, repeat)
+		formattedDump.graphID = formattedRes.graphID
+		writeToFile(resultFile, &formattedRes, false)
+		writeToFile(dumpFile, &formattedDump, true)
+		if qRes.QExecTime == -4 { // Not journaled, so that a resumed run runs it again
+			summary.interrupted = true
+			summary.interruptedAt = &c
+			return results
+		}
+		if qRes.QExecTime == -5 { // Not journaled either
+			recordFailure(c, repeat, i, qRes.Err)
+			if !isWarmup {
+				results = append(results, qRes)
+			}
+			continue
+		}
+		disagreements.observe(i, params, createGraphQuery, queryString, qRes)
+		if !isWarmup { // warm-up runs do not count towards the cutoff
+			policy.record(c, qRes.QExecTime == -1)
+		}
+		progress.record(iterationRecord{N: n, P: p, Repeat: repeat, Iteration: i, Timeout: qRes.QExecTime == -1})
+		summary.queries++
+		if qRes.QExecTime == -1 {
+			summary.timeouts++
+		}
+		if !isWarmup {
+			results = append(results, qRes)
 		}
-		ignore = false
 		//fmt.Println("query executed successfuly")
 	}
+	return results
+}
+
+// Runs queryString, a rendering of q, once, stopping it after the timeout
+func runQuery(ctx context.Context, q utils.Query, queryString string) utils.QueryResult {
+	return runAnswered(ctx, q.Answer(), queryString)
+}
+
+// Runs a query whose result is read as answer, see runQuery
+func runAnswered(ctx context.Context, answer utils.Answer, queryString string) utils.QueryResult {
+	ch := make(chan utils.QueryResult)
+	queryCtx, cancel := context.WithTimeout(ctx, queryTimeout())
+	defer cancel()
+	go utils.ExecuteQuery(queryCtx, db, queryString, answer, ch, memgraph)
+	return <-ch
+}
+
+// Clears the caches of the database before a cold run
+func clearCaches(ctx context.Context) error {
+	switch db.(type) {
+	case *sql.DB:
+		if err := db.(*sql.DB).Close(); err != nil {
+			return err
+		}
+		connectToDuckDB()
+		return nil
+	default:
+		return utils.ClearCaches(ctx, db, memgraph)
+	}
+}
+
+// How long a query may run. In a frontier search, queries are stopped as soon as they go over budget.
+func queryTimeout() time.Duration {
+	if frontier > 0 && frontier < timeout {
+		return frontier
+	}
+	return timeout
 }
 
 //Helper functions
 
 func setUpFlags() {
-	queryFlag := flag.String("query", "", "The query to run. "+allowed_q_desc)
+	queryFlag := flag.String("query", "", "The query to run. "+utils.CatalogDescription())
 	minNodesFlag := flag.Int("minNodes", 10, "How big the smallest random graph should be")
 	maxNodesFlag := flag.Int("maxNodes", 300, "How big the largest random graph should be")
 	incFlag := flag.Int("inc", 10, "How much bigger the graph should be after each iteration")
 	randSeedFlag := flag.Int64("seed", -1, "A seed for the rng. Will be generated using current time if ommited")
 	repeatsFlag := flag.Int("repeats", 5, "How many times each configuration should be tested. A different graph will be generated for each repeat and be tested graphRepeats times.")
-	graphRepeatsFlag := flag.Int("graphRepeats", 5, "How many times each graph should be tested")
+	graphRepeatsFlag := flag.Int("graphRepeats", 5, "How many times each graph should be tested, warm-up runs included")
+	warmupFlag := flag.Int("warmup", 1, "How many of the first runs on each graph are warm-up runs. They are written to the results with warmup=true")
+	coldFlag := flag.Bool("cold", false, "Clear the caches of the database before each measured run")
 	boltPortFlag := flag.Int64("port", 7687, "The server Bolt port.")
 	usernameFlag := flag.String("user", "neo4j", "")
 	passwordFlag := flag.String("pwd", "1234", "")
-	startFlag := flag.Float64("start", 0.1, "")
-	endFlag := flag.Float64("end", 1.0, "")
+	startFlag := flag.String("start", "0.1", "Smallest edge probability")
+	endFlag := flag.String("end", "1.0", "Largest edge probability, edge probabilities going from start to end by 0.1")
+	nSweepFlag := flag.String("n", "", "The graph sizes to test, replacing minNodes, maxNodes and inc. Either a list (10,20,50), a linear range (10:300:10), a geometric range (10:1000:x2) or a log scale range with a number of points (10:1000:log5)")
+	pSweepFlag := flag.String("p", "", "The edge probabilities to test, replacing start and end. Same syntax as -n")
 	memgraphFlag := flag.Bool("memgraph", false, "Use this flag if running memGraph")
-	labeledGraphFlag := flag.Bool("labeled", false, "Use this flag if the query requires a labeled graph")
-	doubleLineGraphFlag := flag.Bool("doubleLine", false, "Use this flag if the query requires a double line graph")
-	edgeValueGraphFlag := flag.Bool("edgeValue", false, "Use this flag if the query require edge values")
-	nodeValueGraphFlag := flag.Bool("nodeValue", false, "Use this flag if the query require node values")
 	postgresFlag := flag.Bool("postgres", false, "Use this flag if running postgres")
 	duckDBFlag := flag.Bool("duckDB", false, "Use this flag if running duckDB")
 	dbNameFlag := flag.String("dbName", "", "Name of the SQL database to use (postgres only)")
+	workloadsFlag := flag.String("workloads", "", "A directory of additional queries defined in files. See workloads/README.md")
+	timeoutFlag := flag.Duration("timeout", 5*time.Minute, "How long a query may run before being recorded as a timeout")
+	cutoffFlag := flag.Int("cutoff", 0, "Skip the graphs larger than a size at which this many queries in a row timed out, for the same edge probability. 0 never skips")
+	frontierFlag := flag.Duration("frontier", 0, "Instead of testing every size, search for each edge probability the largest size whose median execution time is within this budget, between the smallest and largest sizes of the n sweep. 0 tests every size")
+	outputFlag := flag.String("output", "results", "The directory results are written to")
+	orderFlag := flag.String("order", "sequential", fmt.Sprintf("The order in which configurations are tested. One of %v", runOrders))
+	attemptsFlag := flag.Int("attempts", 1, "How many times loading a graph or running a query is tried before being recorded as failed")
+	backoffFlag := flag.Duration("backoff", time.Second, "How long to wait before trying again, doubled after each retry")
+	retryOnFlag := flag.String("retryOn", "connectivity,transient", fmt.Sprintf("The classes of errors that are tried again, among %v", utils.ErrorClasses))
+	loadFlag := flag.Int("load", 0, "Instead of timing queries one at a time, run a load test with this many concurrent workers on one graph of each configuration. 0 disables load tests")
+	loadDurationFlag := flag.Duration("loadDuration", time.Minute, "How long workers issue queries in a load test")
+	loadCountFlag := flag.Int("loadCount", 0, "How many queries are run in a load test, replacing loadDuration. 0 uses loadDuration")
+	loadQueriesFlag := flag.String("loadQueries", "", "Other queries on the same graph family that the workers of a load test pick from along with query, separated by commas")
+	failOnErrorFlag := flag.Bool("failOnError", false, "Exit with code 1 if a query run failed. Failed query runs are recorded in the results either way")
+	cleanUpFlag := flag.Bool("cleanUp", false, "Delete the test graph from the database when the run is interrupted")
+	resumeFlag := flag.String("resume", "", "Resume an interrupted run, given the prefix of its result files (results/<query>_<date>). Cannot be combined with other options")
+	corpusFlag := flag.String("corpus", "", "Draw the graphs from the corpus in this directory instead of generating them. See go run . corpus")
+	preloadedFlag := flag.Bool("preloaded", false, "The graph is already loaded in the database, from files written by go run . export with the same options : only run the queries. The run must test a single graph")
+	endpointsFlag := flag.String("endpoints", "uniform", fmt.Sprintf("How the start/end nodes of graphs and the source/target nodes of queries are picked. One of %v", utils.EndpointStrategies))
 
 	flag.Parse()
-	checkFlags(queryFlag, labeledGraphFlag, doubleLineGraphFlag, edgeValueGraphFlag, nodeValueGraphFlag, postgresFlag, duckDBFlag, dbNameFlag)
+	if *resumeFlag != "" {
+		if flag.NFlag() != 1 {
+			panic(errors.New("-resume cannot be combined with other options, the options of the interrupted run are used"))
+		}
+		header, err := readCheckpointHeader(*resumeFlag)
+		checkErr(err)
+		checkErr(flag.CommandLine.Parse(header.Args))
+		resume = true
+		resumePrefix = *resumeFlag
+	}
+	if *workloadsFlag != "" {
+		workloads, err := utils.LoadWorkloads(*workloadsFlag)
+		checkErr(err)
+		checkErr(utils.RegisterQueries(workloads))
+	}
+	checkFlags(queryFlag, memgraphFlag, postgresFlag, duckDBFlag, dbNameFlag)
 	initRandSeed(randSeedFlag)
+	var err error
+	endpoints, err = utils.ParseEndpointStrategy(*endpointsFlag)
+	checkErr(err)
+	order, err = parseRunOrder(*orderFlag)
+	checkErr(err)
 
-	start_p = *startFlag
-	end_p = *endFlag
 	queryType = *queryFlag
-	minNodes = *minNodesFlag
-	maxNodes = *maxNodesFlag
-	inc = *incFlag
+	sweeps = map[string]sweepSpec{
+		"n": linearSweep(strconv.Itoa(*minNodesFlag), strconv.Itoa(*maxNodesFlag), strconv.Itoa(*incFlag)),
+		"p": linearSweep(*startFlag, *endFlag, "0.1"),
+	}
+	if *nSweepFlag != "" {
+		sweeps["n"], err = parseSweep(*nSweepFlag)
+		checkErr(err)
+	}
+	if *pSweepFlag != "" {
+		sweeps["p"], err = parseSweep(*pSweepFlag)
+		checkErr(err)
+	}
 	repeats = *repeatsFlag
 	graphRepeats = *graphRepeatsFlag
-	labeled = *labeledGraphFlag
-	doubleLine = *doubleLineGraphFlag
-	edgeValue = *edgeValueGraphFlag
-	nodeValue = *nodeValueGraphFlag
+	warmup = *warmupFlag
+	cold = *coldFlag
+	cleanUp = *cleanUpFlag
+	failOnError = *failOnErrorFlag
+	retryPolicy, err = parseRetryPolicy(*attemptsFlag, *backoffFlag, strings.Split(*retryOnFlag, ","))
+	checkErr(err)
+	loadWorkers = *loadFlag
+	loadDuration = *loadDurationFlag
+	loadCount = *loadCountFlag
+	loadQueries = nil
+	if *loadQueriesFlag != "" {
+		loadQueries = strings.Split(*loadQueriesFlag, ",")
+	}
+	checkErr(checkLoadOptions())
+	checkErr(checkRepeatOptions())
 	memgraph = *memgraphFlag
 	postgres = *postgresFlag
 	duckDB = *duckDBFlag
@@ -196,54 +492,67 @@ func setUpFlags() {
 	pwd = *passwordFlag
 	dbName = *dbNameFlag
 	boltPort = *boltPortFlag
+	timeout = *timeoutFlag
+	outputDir = *outputFlag
+	cutoff = *cutoffFlag
+	frontier = *frontierFlag
+	corpus = nil
+	if *corpusFlag != "" {
+		if frontier > 0 {
+			panic(errors.New("frontier searches cannot draw from a corpus"))
+		}
+		corpus, err = openCorpus(*corpusFlag, false)
+		checkErr(err)
+	}
+	preloaded = *preloadedFlag
+	if preloaded && (frontier > 0 || loadWorkers > 0) {
+		panic(errors.New("-preloaded cannot be combined with frontier searches or load tests"))
+	}
 }
 
-func checkFlags(queryFlag *string, labeledGraphFlag *bool, doubleLineGraphFlag *bool, edgeValueGraphFlag *bool, nodeValueGraphFlag *bool, postgresFlag *bool, duckDBFlag *bool, dbNameFlag *string) {
+func checkFlags(queryFlag *string, memgraphFlag *bool, postgresFlag *bool, duckDBFlag *bool, dbNameFlag *string) {
 	if *queryFlag == "" {
 		panic(errors.New("please choose a query to run"))
-	} else if !allowed_queries[*queryFlag] {
-		panic(fmt.Errorf("%v is not a valid query. %v", *queryFlag, allowed_q_desc))
 	}
-
-	if *labeledGraphFlag && !(*queryFlag == "NormalAStarBStar" || *queryFlag == "AutomataAStarBStar" || *queryFlag == "AStarBAStar") {
-		panic(errors.New("you are asking to use a labeled graph with a non-labeled query. Please remove the --labeled flag or change the query"))
+	var found bool
+	query, found = utils.FindQuery(*queryFlag)
+	if !found {
+		panic(fmt.Errorf("%v is not a valid query. %v", *queryFlag, utils.CatalogDescription()))
 	}
 
-	if *edgeValueGraphFlag && !(*queryFlag == "IncreasingPath") {
-		panic(errors.New("you are asking to run a non-edge value query on a graph with edge values. Please remove the --edgeValue flag or change the query"))
-	}
-
-	if *queryFlag == "IncreasingPath" && !(*edgeValueGraphFlag) {
-		panic(errors.New("you are asking to run a query that requires edge values on a graph without edge values. Please add the --edgeValue flag or change the query"))
-	}
-
-	if *nodeValueGraphFlag && !(*queryFlag == "IncreasingNode") {
-		panic(errors.New("you are asking to run a non-node value query on a graph with node values. Please remove the --nodeValue flag or change the query"))
-	}
-
-	if *queryFlag == "IncreasingNode" && !(*nodeValueGraphFlag) {
-		panic(errors.New("you are asking to run a query that requires node values on a graph without node values. Please add the --nodeValue flag or change the query"))
-	}
-
-	if (*queryFlag == "NormalAStarBStar" || *queryFlag == "AutomataAStarBStar" || *queryFlag == "AStarBAStar") && !*labeledGraphFlag {
-		panic(errors.New("you are asking to run a labeled query on a non-labled graph. Please add the --labeled flag or change the query"))
+	if (*memgraphFlag && *postgresFlag) || (*memgraphFlag && *duckDBFlag) || (*postgresFlag && *duckDBFlag) {
+		panic(errors.New("please choose only one system"))
 	}
 
-	if *queryFlag == "SubsetSum" && !*doubleLineGraphFlag {
-		panic(errors.New("you are asking to run a value-dependant query on a non-valued graph. Please add the --doubleLine flag or change the query"))
+	switch {
+	case *memgraphFlag:
+		backend = utils.Memgraph
+	case *postgresFlag:
+		backend = utils.Postgres
+	case *duckDBFlag:
+		backend = utils.DuckDB
+	default:
+		backend = utils.Neo4j
 	}
 
-	if (*postgresFlag || *duckDBFlag) && !(*queryFlag == "SubsetSum" || *queryFlag == "hamil" || *queryFlag == "euler" || *queryFlag == "AStarBAStar") {
-		panic(errors.New("only subset sum, hamiltonian path and eulerian path queries are implemented for postgres and duckDB. Please change the query or switch to Neo4j"))
+	if !query.Supports(backend) {
+		panic(fmt.Errorf("%v is not implemented for %v. Please change the query or the system", query.Name(), backend))
 	}
 
 	if *postgresFlag && *dbNameFlag == "" {
 		panic(errors.New("please provide the name of the database to run the tests on. The database must be created before running this program"))
 	}
+}
 
-	if *postgresFlag && *duckDBFlag {
-		panic(errors.New("please choose only one system"))
+// Checks that every graph gets at least one measured run, so that a run does not end with warm-up runs only
+func checkRepeatOptions() error {
+	if graphRepeats < 1 {
+		return fmt.Errorf("each graph must be tested at least once, got graphRepeats=%v", graphRepeats)
 	}
+	if warmup < 0 || warmup >= graphRepeats {
+		return fmt.Errorf("the number of warm-up runs must be between 0 and graphRepeats-1 (%v), got %v", graphRepeats-1, warmup)
+	}
+	return nil
 }
 
 func initRandSeed(randSeedFlag *int64) {
@@ -252,116 +561,110 @@ func initRandSeed(randSeedFlag *int64) {
 	} else {
 		seed = *randSeedFlag
 	}
-	rand.New(rand.NewSource(seed))
+	utils.SetSeed(seed)
 }
 
-func formatTestResult(qRes utils.QueryResult, n int, p float64, createGraphQuery []string, query string) (testResult, testResult) {
-	formattedRes := testResult{nodes: n, probability: p, queryResult: qRes, graph: "", query: ""}
-
-	createGraphQueryString := ""
-	for _, subQuery := range createGraphQuery {
-		createGraphQueryString += subQuery + "\n"
-	}
-	createGraphQueryString += "\n"
-	formattedDump := testResult{nodes: n, probability: p, queryResult: qRes, graph: createGraphQueryString, query: query}
+func formatTestResult(qRes utils.QueryResult, n int, p float64, params utils.QueryParams, graphHash string, query string, warmup bool) (testResult, testResult) {
+	formattedRes := testResult{nodes: n, probability: p, queryResult: qRes, params: params, warmup: warmup, graphHash: graphHash, query: ""}
+	formattedDump := testResult{nodes: n, probability: p, queryResult: qRes, params: params, warmup: warmup, graphHash: graphHash, query: query}
 	return formattedRes, formattedDump
 }
 
-func createFiles(queryType string) (*os.File, *os.File) {
-	timeLayout := "2006-02-01--15:04:05"
-	resultFile, err := os.Create(fmt.Sprintf("results/%v_%v.csv", queryType, time.Now().Format(timeLayout)))
+// Stores graph in the graph directory of the run, unless it is already there, and returns the hash results reference it by
+func storeGraph(graph *utils.Graph) string {
+	hash, err := utils.SaveGraph(filepath.Join(outputDir, "graphs"), graph)
 	checkErr(err)
-	_, err = resultFile.WriteString("order,edge probability,query execution time,found,timestamp\n")
+	return hash
+}
+
+// Creates the result and dump files, or reopens them to append to them when resuming
+func createFiles(filePrefix string, resuming bool) (*os.File, *os.File) {
+	if resuming {
+		resultFile, err := reopenForAppend(filePrefix+".csv", nil)
+		checkErr(err)
+		dumpFile, err := reopenForAppend(filePrefix+"_dump.txt", func(line string) bool { // entries end with a separator, after the header lines
+			return line == "------" || strings.HasPrefix(line, "seed = ") || strings.HasPrefix(line, "system = ")
+		})
+		checkErr(err)
+		return resultFile, dumpFile
+	}
+	resultFile, err := os.Create(filePrefix + ".csv")
 	checkErr(err)
-	dumpFile, err := os.Create(fmt.Sprintf("results/%v_%v_dump.txt", queryType, time.Now().Format(timeLayout)))
+	header := "order,edge probability,query execution time,found,timestamp,warmup,error,retries,graph hash"
+	if paired {
+		header = "graph,system," + header
+	}
+	for _, param := range query.Parameters() {
+		header += "," + param
+	}
+	_, err = resultFile.WriteString(header + "\n")
+	checkErr(err)
+	dumpFile, err := os.Create(filePrefix + "_dump.txt")
 	checkErr(err)
 	_, err = dumpFile.WriteString(fmt.Sprintf("seed = %v\n", seed))
 	checkErr(err)
+	if !paired { // rows of paired runs name their system
+		_, err = dumpFile.WriteString(fmt.Sprintf("system = %v\n", backend))
+		checkErr(err)
+	}
 	return resultFile, dumpFile
 }
 
 func writeToFile(fileLocation *os.File, data *testResult, dump bool) {
 	timeLayout := "15:04:05"
-	qExecTime := strconv.Itoa(data.queryResult.QExecTime)
-	if qExecTime == "-1" {
-		qExecTime = "timeout"
+	qExecTime := outcome(data.queryResult)
+	errorMessage := ""
+	if data.queryResult.Err != nil {
+		errorMessage = csvQuote(data.queryResult.Err.Error())
 	}
-	if qExecTime == "-2" {
-		qExecTime = "outOfMemory"
+	toWrite := fmt.Sprintf("%v,%v,%v,%v,%v,%v,%v,%v,%v", data.nodes, data.probability, qExecTime, data.queryResult.Found, time.Now().Format(timeLayout), data.warmup, errorMessage, data.retries, data.graphHash)
+	if paired {
+		toWrite = fmt.Sprintf("%v,%v,", data.graphID, backend) + toWrite
 	}
-	toWrite := fmt.Sprintf("%v,%v,%v,%v,%v\n", data.nodes, data.probability, qExecTime, data.queryResult.Found, time.Now().Format(timeLayout))
-	_, err := fileLocation.WriteString(toWrite)
+	if !dump { // The dump already holds the full query text
+		for i := range query.Parameters() { // left empty for the runs that picked no nodes, as skipped and failed runs
+			toWrite += ","
+			if i < len(data.params) {
+				toWrite += fmt.Sprint(data.params[i])
+			}
+		}
+	}
+	_, err := fileLocation.WriteString(toWrite + "\n")
 	checkErr(err)
 	if dump {
-		toWrite = fmt.Sprintf("%v\n%v\n------\n", data.graph, data.query)
+		toWrite = fmt.Sprintf("\n%v\n------\n", data.query) // The graph is stored once, under its hash
 		_, err = fileLocation.WriteString(toWrite)
 		checkErr(err)
 	}
 }
 
-func createRandomQuery(n int) string {
-	switch queryType {
-	case "tdp":
-		return utils.RandomTwoDisjointPathQuery(n)
-	case "hamil":
-		if memgraph {
-			return utils.HamiltonianPathMemgraph()
-		} else if postgres || duckDB {
-			return utils.HamiltonianSQL()
-		} else {
-			return utils.HamiltonianPath()
-		}
-	case "enum":
-		return utils.EnumeratePaths(n)
-	case "any":
-		return utils.FindAnyPath(n)
-	case "tgfree":
-		return utils.TriangleFree()
-	case "euler":
-		if memgraph {
-			return utils.EulerianTrailMemgraph()
-		} else if postgres  || duckDB {
-			return utils.EulerianSQL()
-		} else {
-			return utils.EulerianTrail()
-		}
-	case "NormalAStarBStar":
-		return utils.NormalAStarBStar()
-	case "AutomataAStarBStar":
-		return utils.AutomataAStarBStar()
-	case "SmartTDP":
-		return utils.SmartRandomTwoDisjointPathQuery(n)
-	case "ShortestHamil":
-		return utils.ShortestHamiltonian(n)
-	case "SubsetSum":
-		if postgres  || duckDB {
-			return utils.SubsetSumSQL(n)
-		} else {
-			return utils.SubsetSum(n)
-		}
-	case "AStarBAStar":
-		if postgres  {
-			return utils.AStarBAStarSQL()
-		} else if duckDB {
-			return utils.AStarBAStarDuckDB()
-		} else {
-			return utils.AStarBAStar()
-		}
-	case "IncreasingPath":
-		if postgres  || duckDB {
-			return "invalid"
-		} else {
-			return utils.IncreasingPath()
+// Quotes s as a CSV field, on a single line
+func csvQuote(s string) string {
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\r", " "), "\n", " ")
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func parseRetryPolicy(attempts int, backoff time.Duration, classes []string) (utils.RetryPolicy, error) {
+	policy := utils.RetryPolicy{MaxAttempts: attempts, Backoff: backoff}
+	if attempts < 1 {
+		return policy, errors.New("there must be at least one attempt")
+	}
+	for _, name := range classes {
+		if name == "" {
+			continue
 		}
-	case "IncreasingNode":
-		if postgres  || duckDB {
-			return "invalid"
-		} else {
-			return utils.IncreasingPathNode()
+		class, err := utils.ParseErrorClass(strings.TrimSpace(name))
+		if err != nil {
+			return policy, err
 		}
-	default:
-		return "invalid"
+		policy.Classes = append(policy.Classes, class)
 	}
+	return policy, nil
 }
 
 func checkErr(err error) {
@@ -372,27 +675,46 @@ func checkErr(err error) {
 
 // Custom types and global variables
 
+// What the run completed, printed if it is interrupted
+type runSummary struct {
+	filePrefix    string // of the last test suite
+	queries       int    // query runs written to the results, warm-up runs included
+	timeouts      int
+	rounds        int // graphs on which every query run completed
+	interrupted   bool
+	interruptedAt *cell // the configuration of the interrupted query, if a query was running
+	failures      []failure
+	disagreements []string // the reproducers of the instances that paired backends, or a backend and the reference solver, answered differently
+}
+
+// A query run that failed with an error
+type failure struct {
+	filePrefix string
+	c          cell
+	repeat     int
+	iteration  int
+	err        error
+}
+
 type testResult struct {
 	nodes       int
 	probability float64
 	queryResult utils.QueryResult
-	graph       string
+	params      utils.QueryParams
+	warmup      bool
+	retries     int    // how many times loading the graph or running the query was tried again
+	graphID     string // in paired runs
+	graphHash   string // of the graph in the graph directory of the run, see storeGraph
 	query       string
 }
 
 var queryType string
-var minNodes int
-var maxNodes int
-var inc int
-var start_p float64
-var end_p float64
+var sweeps map[string]sweepSpec
 var seed int64
 var repeats int
 var graphRepeats int
-var labeled bool
-var doubleLine bool
-var edgeValue bool
-var nodeValue bool
+var warmup int
+var cold bool
 var memgraph bool
 var postgres bool
 var duckDB bool
@@ -400,36 +722,26 @@ var username string
 var pwd string
 var dbName string
 var boltPort int64
+var timeout time.Duration
+var outputDir string
+var cutoff int
+var frontier time.Duration
+var resume bool
+var cleanUp bool
+var failOnError bool
+var paired bool
+var disagreements *disagreementDetector
+var corpus *graphCorpus // nil unless graphs are drawn from a corpus
+var preloaded bool
+var retryPolicy utils.RetryPolicy
+var loadWorkers int
+var loadDuration time.Duration
+var loadCount int
+var loadQueries []string
+var summary runSummary
+var resumePrefix string
 var db interface{}
-
-var allowed_queries = map[string]bool{
-	"tdp":                true,
-	"hamil":              true,
-	"enum":               true,
-	"any":                true,
-	"tgfree":             true,
-	"euler":              true,
-	"NormalAStarBStar":   true,
-	"AutomataAStarBStar": true,
-	"SmartTDP":           true,
-	"ShortestHamil":      true,
-	"SubsetSum":          true,
-	"AStarBAStar":        true,
-	"IncreasingPath":     true,
-	"IncreasingNode":     true,
-}
-var allowed_q_desc = `Available queries are :
-'tdp' : two disjoint paths
-'hamil' : hamiltonian path
-'enum' : trail enumeration
-'any' : any path
-'tgfree' : triangle free
-'euler' : eulerian trail
-'NormalAStarBStar' : a*b*, the old fashioned way
-'AutomataAStarBStar' : a*b*, the automata way
-'SmartTDP' : two disjoint path using Cypher trail semantics
-'ShortestHamil': Shortest path variant of Hamiltonian path
-'SubsetSum' : Subset sum query
-'AStarBAStar' : a*ba*
-'IncreasingPath' : Value increasing along the edges
-'IncreasingNode': Value increasing along the nodes`
+var query utils.Query
+var backend utils.Backend
+var endpoints utils.EndpointStrategy
+var order runOrder
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// What must still hold on the minimized instance
+type minimizePredicate string
+
+const (
+	timesOut          minimizePredicate = "timeout" // the query times out on the backend
+	crashes           minimizePredicate = "crash"   // the query fails or runs out of memory on the backend
+	differsFromOracle minimizePredicate = "oracle"  // the backend answers differently from the reference solver
+)
+
+var minimizePredicates = []minimizePredicate{timesOut, crashes, differsFromOracle}
+
+func parseMinimizePredicate(name string) (minimizePredicate, error) {
+	for _, p := range minimizePredicates {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown predicate %q, expected one of %v", name, minimizePredicates)
+}
+
+// Whether the predicate holds for the result of the query on g with the given nodes
+func (p minimizePredicate) holds(qRes utils.QueryResult, oracle utils.Oracle, g *utils.Graph, nodes utils.QueryParams) bool {
+	switch p {
+	case timesOut:
+		return qRes.QExecTime == -1
+	case crashes:
+		return qRes.QExecTime == -5 || qRes.QExecTime == -2
+	default:
+		found, solved := oracle(g, nodes)
+		return qRes.QExecTime >= 0 && solved && qRes.Found != found
+	}
+}
+
+// Minimizes a graph on which a query times out, crashes or answers differently from its reference solver on some backend,
+// by delta debugging, and writes the smallest graph found along with the query
+func minimize(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	queryFlag := flags.String("query", "", "The query to run")
+	graphFlag := flags.String("graph", "", "A file holding the script creating the graph, as written to dump files")
+	nodesFlag := flags.String("nodes", "", "The nodes picked by the query, if any, separated by commas")
+	predicateFlag := flags.String("predicate", "", fmt.Sprintf("What must still hold on the minimized graph. One of %v", minimizePredicates))
+	systemFlag := flags.String("system", string(utils.DuckDB), "The system to run the query on : neo4j, memgraph, postgres or duckDB")
+	portFlag := flags.Int64("port", 7687, "The server Bolt port")
+	userFlag := flags.String("user", "neo4j", "")
+	pwdFlag := flags.String("pwd", "1234", "")
+	dbNameFlag := flags.String("dbName", "", "Name of the SQL database to use (postgres only)")
+	timeoutFlag := flags.Duration("timeout", 5*time.Minute, "How long a query may run before being recorded as a timeout")
+	outputFlag := flags.String("output", "", "The file the minimized graph is written to. <graph>_min.txt by default")
+	checkErr(flags.Parse(args))
+
+	q, found := utils.FindQuery(*queryFlag)
+	if !found {
+		panic(fmt.Errorf("%v is not a valid query. %v", *queryFlag, utils.CatalogDescription()))
+	}
+	predicate, err := parseMinimizePredicate(*predicateFlag)
+	checkErr(err)
+	oracle, hasOracle := utils.FindOracle(q.Name())
+	if predicate == differsFromOracle && !hasOracle {
+		panic(fmt.Errorf("%v has no reference solver", q.Name()))
+	}
+	if *graphFlag == "" {
+		panic(errors.New("please provide the file holding the script of the graph (-graph)"))
+	}
+	content, err := os.ReadFile(*graphFlag)
+	checkErr(err)
+	g, err := utils.ParseGraphScript(strings.Split(string(content), "\n"))
+	checkErr(err)
+	nodes := make(utils.QueryParams, 0)
+	if *nodesFlag != "" {
+		for _, node := range strings.Split(*nodesFlag, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(node))
+			checkErr(err)
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) != len(q.Parameters()) {
+		panic(fmt.Errorf("%v needs %v nodes (%v)", q.Name(), len(q.Parameters()), strings.Join(q.Parameters(), ",")))
+	}
+	output := *outputFlag
+	if output == "" {
+		output = strings.TrimSuffix(*graphFlag, ".txt") + "_min.txt"
+	}
+
+	useBackend(backendSpec{System: *systemFlag, Port: *portFlag, User: *userFlag, Pwd: *pwdFlag, DBName: *dbNameFlag})
+	if !q.Supports(backend) {
+		panic(fmt.Errorf("%v is not implemented for %v", q.Name(), backend))
+	}
+	timeout = *timeoutFlag
+	connect(ctx)
+	defer closeDB(context.Background())
+
+	tested := 0
+	var loadErr error // graphs that cannot be loaded tell nothing about the query, so the minimization stops
+	failing := func(candidate *utils.Graph, candidateNodes utils.QueryParams) bool {
+		if ctx.Err() != nil || loadErr != nil {
+			return false
+		}
+		tested++
+		fmt.Printf("\r[%v]Tested %v graphs, currently %v nodes and %v edges", time.Now().Format("2006-01-02T15:04:05"), tested, candidate.Nodes, len(candidate.Edges))
+		queryString, err := q.RenderNodes(backend, candidate, candidateNodes)
+		checkErr(err)
+		if err := utils.SetUpDB(context.WithoutCancel(ctx), db, utils.GraphScript(backend, candidate), candidate.Nodes); err != nil {
+			loadErr = err
+			return false
+		}
+		return predicate.holds(runQuery(ctx, q, queryString), oracle, candidate, candidateNodes)
+	}
+	holds := failing(g, nodes)
+	if loadErr != nil {
+		panic(fmt.Errorf("\nthe given graph could not be loaded on %v : %w", backend, loadErr))
+	}
+	if !holds {
+		panic(fmt.Errorf("\n%v does not hold on the given graph for %v on %v", predicate, q.Name(), backend))
+	}
+	minimized, minimizedNodes := utils.Minimize(g, nodes, failing)
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted, the graph was not minimized")
+		closeDB(context.Background())
+		os.Exit(130)
+	}
+	if loadErr != nil {
+		panic(fmt.Errorf("\na graph could not be loaded on %v, as when the query brought the server down, so the graph was not minimized : %w", backend, loadErr))
+	}
+
+	queryString, err := q.RenderNodes(backend, minimized, minimizedNodes)
+	checkErr(err)
+	result := fmt.Sprintf("%v on %v : %v, minimized from %v nodes and %v edges to %v nodes and %v edges\n", q.Name(), backend, predicate, g.Nodes, len(g.Edges), minimized.Nodes, len(minimized.Edges))
+	if len(minimizedNodes) > 0 {
+		result += fmt.Sprintf("nodes %v\n", minimizedNodes)
+	}
+	result += fmt.Sprintf("\n%v\n\n%v\n", strings.Join(utils.GraphScript(backend, minimized), "\n"), queryString)
+	checkErr(os.WriteFile(output, []byte(result), 0644))
+	fmt.Printf("\nMinimized graph written to %v\n", output)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The order in which the rounds of a test suite are run
+type runOrder string
+
+const (
+	sequentialOrder  runOrder = "sequential" // every repeat of a configuration in a row, sizes increasing within each edge probability
+	shuffledOrder    runOrder = "shuffle"    // rounds in a random order drawn from the seed of the run
+	interleavedOrder runOrder = "interleave" // one repeat of every configuration, then the next repeat of every configuration...
+)
+
+var runOrders = []runOrder{sequentialOrder, shuffledOrder, interleavedOrder}
+
+func parseRunOrder(name string) (runOrder, error) {
+	for _, o := range runOrders {
+		if string(o) == name {
+			return o, nil
+		}
+	}
+	return "", fmt.Errorf("unknown order %q, expected one of %v", name, runOrders)
+}
+
+// One graph of a configuration, tested graphRepeats times
+type round struct {
+	c      cell
+	repeat int
+}
+
+// Returns the rounds of the given configurations in the given order
+func (o runOrder) rounds(cells []cell) []round {
+	rounds := make([]round, 0, len(cells)*repeats)
+	if o == interleavedOrder {
+		for reps := 0; reps < repeats; reps++ {
+			for _, c := range cells {
+				rounds = append(rounds, round{c: c, repeat: reps})
+			}
+		}
+		return rounds
+	}
+	for _, c := range cells {
+		for reps := 0; reps < repeats; reps++ {
+			rounds = append(rounds, round{c: c, repeat: reps})
+		}
+	}
+	if o == shuffledOrder {
+		shuffle := rand.New(rand.NewSource(seed))
+		shuffle.Shuffle(len(rounds), func(i, j int) { rounds[i], rounds[j] = rounds[j], rounds[i] })
+	}
+	return rounds
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// One of the backends of a paired run, with its own connection, journal and cutoff policy
+type pairedBackend struct {
+	spec     backendSpec
+	db       interface{}
+	progress *checkpoint
+	policy   *cutoffPolicy
+}
+
+// Makes b the current backend
+func (b *pairedBackend) activate() {
+	useBackend(b.spec)
+	db = b.db
+}
+
+// Stores the connection of the current backend back in b, as cold runs may reopen it
+func (b *pairedBackend) release() {
+	b.db = db
+}
+
+// Connects to every backend of the experiment, runs every query on the same graphs on all of them, then closes the connections
+func runPaired(ctx context.Context, spec experimentSpec) {
+	backends := make([]*pairedBackend, 0)
+	for _, b := range spec.Backends {
+		useBackend(b)
+		connect(ctx)
+		backends = append(backends, &pairedBackend{spec: b, db: db})
+	}
+	for _, name := range spec.Queries {
+		if summary.interrupted {
+			break
+		}
+		query, _ = utils.FindQuery(name)
+		queryType = name
+		sweeps = spec.sweeps(query.Graph())
+		initRandSeed(&spec.Seed)
+		pairedSuite(ctx, fmt.Sprintf("%v/%v_paired", outputDir, name), backends)
+		fmt.Println()
+	}
+	for _, b := range backends {
+		b.activate()
+		shutDown()
+	}
+}
+
+// Runs every configuration of the current query on every backend, generating each graph once and loading it in all of them.
+// Results are written to filePrefix.csv and filePrefix_dump.txt, keyed by graph id and system,
+// and the progress of every backend is journaled to filePrefix_<system>_checkpoint.jsonl.
+func pairedSuite(ctx context.Context, filePrefix string, backends []*pairedBackend) {
+	resuming := resume && fileExists(filePrefix+".csv")
+	summary.filePrefix = filePrefix
+	systems := make([]utils.Backend, 0)
+	for _, b := range backends {
+		journal := fmt.Sprintf("%v_%v", filePrefix, b.spec.System)
+		if resuming && fileExists(checkpointFile(journal)) {
+			b.progress = resumeCheckpoint(journal)
+		} else {
+			b.progress = createCheckpoint(journal, nil)
+		}
+		defer b.progress.Close()
+		b.policy = newCutoffPolicy(cutoff)
+		systems = append(systems, utils.Backend(b.spec.System))
+	}
+	resultFile, dumpFile := createFiles(filePrefix, resuming)
+	defer resultFile.Close()
+	defer dumpFile.Close()
+	disagreements = newDisagreementDetector(filePrefix, systems, resuming)
+	defer func() {
+		disagreements.Close()
+		disagreements = nil
+	}()
+
+	for _, b := range backends {
+		b.activate()
+		_, err := retryPolicy.Do(ctx, func() error {
+			return utils.CleanUpDB(ctx, db, -1)
+		})
+		checkErr(err)
+	}
+
+	generator, err := utils.PairedGenerator(query.Graph(), systems)
+	checkErr(err)
+	cells, err := sweepCells(query.Graph(), sweeps)
+	checkErr(err)
+	checkErr(corpus.covers(query.Graph(), cells, repeats))
+	rounds := order.rounds(cells)
+	for _, b := range backends {
+		b.progress.replay(rounds, b.policy)
+	}
+	for _, r := range rounds {
+		graph := roundGraph(generator, r.c, r.repeat)
+		tested := false
+		for _, b := range backends {
+			if ctx.Err() != nil {
+				summary.interrupted = true
+				break
+			}
+			if b.progress.roundCompleted(r.c, r.repeat) {
+				continue
+			}
+			b.activate()
+			if b.policy.dominated(r.c) {
+				skipRound(r.c, r.repeat, resultFile, b.progress)
+				continue
+			}
+			// Queries are rendered on the generated graph, so that every backend gets the same endpoints
+			createGraphQuery := utils.GraphScript(backend, utils.PairedView(graph, backend))
+			loadRetries, err := loadGraph(ctx, createGraphQuery, r.c.n)
+			if err != nil {
+				failRound(r.c, r.repeat, graph, err, loadRetries, resultFile)
+				continue
+			}
+			testRound(ctx, r.c, r.repeat, graph, createGraphQuery, loadRetries, resultFile, dumpFile, b.policy, b.progress)
+			b.release()
+			tested = true
+			if summary.interrupted {
+				break
+			}
+		}
+		disagreements.check(r.c, r.repeat, graph)
+		if summary.interrupted {
+			return
+		}
+		if tested {
+			summary.rounds++
+		}
+	}
+}
+
+// Returns the id of the graph of a round in the results of a paired run, or "" outside of paired runs
+func pairedGraphID(c cell, repeat int) string {
+	if !paired {
+		return ""
+	}
+	return graphID(c, repeat)
+}
+
+// The id of the graph of a repeat of a cell, naming it in paired results and in reproducers
+func graphID(c cell, repeat int) string {
+	return fmt.Sprintf("n%v-p%v-r%v", c.n, c.p, repeat)
+}
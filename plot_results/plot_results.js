@@ -162,6 +162,8 @@ function formatMedianLineChart(results){
   element_position = new Map()
 
   results.data.forEach(res => {
+    if (res["warmup"] === "true" || res["query execution time"] === "interrupted" || res["query execution time"] === "error") return
+
     if (!element_position.has(res["order"])) {
       unformatted.push({
         order: res["order"],
@@ -174,7 +176,7 @@ function formatMedianLineChart(results){
     elementPos = element_position.get(res["order"])
     unformatted[elementPos].totalRuns += 1
 
-    if (res["query execution time"] !== "timeout") {
+    if (res["query execution time"] !== "timeout" && res["query execution time"] !== "skipped") {
       unformatted[elementPos].execTimes.push(parseInt(res["query execution time"]))
     }
   });
@@ -241,6 +243,8 @@ function formatTimeoutBarChart(results){
   element_position = new Map()
 
   results.data.forEach(res => {
+    if (res["warmup"] === "true" || res["query execution time"] === "interrupted" || res["query execution time"] === "error") return
+
     if (!element_position.has(res["order"])) {
       unformatted.push({
         order: res["order"],
@@ -253,7 +257,7 @@ function formatTimeoutBarChart(results){
     elementPos = element_position.get(res["order"])
     unformatted[elementPos].total_runs += 1
 
-    if (res["query execution time"] == "timeout") {
+    if (res["query execution time"] == "timeout" || res["query execution time"] == "skipped") {
       unformatted[elementPos].timeouts +=1
     }
   });
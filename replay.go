@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// Which entries of a dump file are replayed
+type replayFilter struct {
+	orders   map[int]bool
+	probs    map[float64]bool
+	outcomes map[string]bool
+	found    string
+	system   string
+	warmups  bool
+}
+
+// Whether e is selected by f. Empty sets select everything.
+func (f replayFilter) selects(e dumpEntry) bool {
+	if len(f.orders) > 0 && !f.orders[e.n] {
+		return false
+	}
+	if len(f.probs) > 0 && !f.probs[e.p] {
+		return false
+	}
+	if len(f.outcomes) > 0 && !f.outcomes[outcomeKind(e.result)] {
+		return false
+	}
+	if f.found != "" && f.found != strconv.FormatBool(e.result.Found) {
+		return false
+	}
+	if f.system != "" && f.system != e.system {
+		return false
+	}
+	return f.warmups || !e.warmup
+}
+
+// The outcome of a result, completed runs all being "completed"
+func outcomeKind(res utils.QueryResult) string {
+	if res.QExecTime >= 0 {
+		return "completed"
+	}
+	return outcome(res)
+}
+
+// Whether the answer of e was recorded. Older duckDB runs went through explain analyze,
+// which always returns rows, so their answer is always true.
+func answerRecorded(e dumpEntry) bool {
+	return recordedBackends(e)[0] != utils.DuckDB || !strings.HasPrefix(strings.ToLower(e.query), "explain analyze")
+}
+
+// Whether a replayed result matches the recorded one : same outcome and, for completed runs whose answer was recorded, same answer
+func sameOutcome(e dumpEntry, replayed utils.QueryResult) bool {
+	if outcomeKind(e.result) != outcomeKind(replayed) {
+		return false
+	}
+	return e.result.QExecTime < 0 || !answerRecorded(e) || e.result.Found == replayed.Found
+}
+
+// Returns the backends that can run a graph script as it was written
+func scriptBackends(script []string) []utils.Backend {
+	text := strings.Join(script, "\n")
+	switch {
+	case !strings.Contains(text, "INSERT INTO") && !strings.Contains(text, "CREATE TABLE"):
+		return []utils.Backend{utils.Neo4j, utils.Memgraph}
+	case strings.Contains(text, "nextval('serial')"):
+		return []utils.Backend{utils.DuckDB}
+	case strings.Contains(text, "id serial"):
+		return []utils.Backend{utils.Postgres}
+	default:
+		return []utils.Backend{utils.Postgres, utils.DuckDB}
+	}
+}
+
+// Returns the backends e may have been recorded on : its system if it is known, or else those that can run its script
+func recordedBackends(e dumpEntry) []utils.Backend {
+	if e.system != "" {
+		return []utils.Backend{utils.Backend(e.system)}
+	}
+	return scriptBackends(e.script)
+}
+
+func cypherBackend(b utils.Backend) bool {
+	return b == utils.Neo4j || b == utils.Memgraph
+}
+
+// Returns the script loading the graph of e on backend b, along with the graph if it was needed :
+// the script of the graph stored in graphDir under the hash of e, or for older dumps the recorded script if b can run it,
+// or else one generated from the parsed graph
+func replayScript(e dumpEntry, b utils.Backend, graphDir string) ([]string, *utils.Graph, error) {
+	if e.graphHash != "" {
+		g, err := utils.LoadGraph(graphDir, e.graphHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		return utils.GraphScript(b, utils.PairedView(g, b)), g, nil
+	}
+	for _, runs := range scriptBackends(e.script) {
+		if runs == b {
+			return e.script, nil, nil
+		}
+	}
+	g, err := utils.ParseGraphScript(e.script)
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.Nodes < e.n { // isolated nodes are not named by every script
+		g.Nodes = e.n
+	}
+	return utils.GraphScript(b, g), g, nil
+}
+
+// Returns the text of the query of e to run on backend b, rendering q again if the recorded query is in another language.
+// g is the parsed graph of e, if it was needed to load it.
+func replayQuery(e dumpEntry, q utils.Query, g *utils.Graph, b utils.Backend) (string, error) {
+	if cypherBackend(recordedBackends(e)[0]) != cypherBackend(b) {
+		if q == nil {
+			return "", fmt.Errorf("the recorded query is not written for %v, please name it with -query", b)
+		}
+		if len(q.Parameters()) > 0 {
+			return "", fmt.Errorf("%v picks nodes, which are not recorded in dumps, so it can only be replayed in its own language", q.Name())
+		}
+		if g == nil {
+			var err error
+			if g, err = utils.ParseGraphScript(e.script); err != nil {
+				return "", err
+			}
+		}
+		return q.RenderNodes(b, g, nil)
+	}
+	// Postgres queries are timed with explain analyze, duckDB ones are not
+	text := strings.TrimPrefix(strings.TrimPrefix(e.query, "explain analyze "), "EXPLAIN ANALYZE ")
+	if b == utils.Postgres {
+		text = "explain analyze " + text
+	}
+	return text, nil
+}
+
+// Returns the system named by a dump file or its directory, such as results/duckDB_AStarBAStar/0.3_dump.txt
+func guessDumpSystem(name string) (utils.Backend, bool) {
+	for _, part := range []string{filepath.Base(name), filepath.Base(filepath.Dir(name))} {
+		for _, token := range strings.Split(part, "_") {
+			for _, b := range []utils.Backend{utils.Neo4j, utils.Memgraph, utils.Postgres, utils.DuckDB} {
+				if strings.EqualFold(token, string(b)) {
+					return b, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Returns the query named by a dump file or its directory, such as results/duckDB_AStarBAStar/0.3_dump.txt or hamil_0.1_dump.txt
+func guessDumpQuery(name string) (utils.Query, bool) {
+	for _, part := range []string{filepath.Base(name), filepath.Base(filepath.Dir(name))} {
+		for _, token := range strings.Split(part, "_") {
+			if q, found := utils.FindQuery(token); found {
+				return q, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Returns the set of the items of a comma separated list
+func parseList[T comparable](list string, parse func(string) (T, error)) map[T]bool {
+	set := make(map[T]bool)
+	if list == "" {
+		return set
+	}
+	for _, item := range strings.Split(list, ",") {
+		v, err := parse(strings.TrimSpace(item))
+		checkErr(err)
+		set[v] = true
+	}
+	return set
+}
+
+// Runs again the query runs recorded in a dump file, selected by filters, on any backend,
+// and writes how the new outcomes compare with the recorded ones
+func replay(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	dumpFlag := flags.String("dump", "", "The dump file to replay")
+	nFlag := flags.String("n", "", "Only replay graphs of these orders, separated by commas")
+	pFlag := flags.String("p", "", "Only replay graphs of these edge probabilities, separated by commas")
+	outcomeFlag := flags.String("outcome", "", "Only replay runs with these recorded outcomes, separated by commas : completed, timeout, outOfMemory, skipped, interrupted or error")
+	foundFlag := flags.String("found", "", "Only replay runs that recorded this answer : true or false")
+	dumpSystemFlag := flags.String("dumpSystem", "", "Only replay runs of this system, for dumps of paired runs or runs of several systems")
+	warmupFlag := flags.Bool("warmup", false, "Also replay warm-up runs")
+	queryFlag := flags.String("query", "", "The recorded query, which tells how its result is read and lets it be rendered again for a system of another language. Guessed from the name of the dump by default")
+	systemFlag := flags.String("system", string(utils.DuckDB), "The system to replay on : neo4j, memgraph, postgres or duckDB")
+	portFlag := flags.Int64("port", 7687, "The server Bolt port")
+	userFlag := flags.String("user", "neo4j", "")
+	pwdFlag := flags.String("pwd", "1234", "")
+	dbNameFlag := flags.String("dbName", "", "Name of the SQL database to use (postgres only)")
+	timeoutFlag := flags.Duration("timeout", 5*time.Minute, "How long a query may run before being recorded as a timeout")
+	outputFlag := flags.String("output", "", "The file the comparison is written to. <dump>_replay.csv by default")
+	checkErr(flags.Parse(args))
+
+	if *dumpFlag == "" {
+		panic(errors.New("please provide the dump file to replay (-dump)"))
+	}
+	if *foundFlag != "" && *foundFlag != "true" && *foundFlag != "false" {
+		panic(fmt.Errorf("found must be true or false, got %v", *foundFlag))
+	}
+	filter := replayFilter{
+		orders:   parseList(*nFlag, strconv.Atoi),
+		probs:    parseList(*pFlag, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }),
+		outcomes: parseList(*outcomeFlag, func(s string) (string, error) { return s, nil }),
+		found:    *foundFlag,
+		system:   *dumpSystemFlag,
+		warmups:  *warmupFlag,
+	}
+	var q utils.Query
+	if *queryFlag != "" {
+		var found bool
+		if q, found = utils.FindQuery(*queryFlag); !found {
+			panic(fmt.Errorf("%v is not a valid query. %v", *queryFlag, utils.CatalogDescription()))
+		}
+	} else if guessed, found := guessDumpQuery(*dumpFlag); found {
+		q = guessed
+	}
+	answer := utils.AnyRow
+	if q != nil {
+		answer = q.Answer()
+	}
+
+	_, entries, err := readDump(*dumpFlag)
+	checkErr(err)
+	if guessed, found := guessDumpSystem(*dumpFlag); found {
+		for i := range entries {
+			if entries[i].system == "" {
+				entries[i].system = string(guessed)
+			}
+		}
+	}
+	graphDir := filepath.Join(filepath.Dir(*dumpFlag), "graphs")
+	selected := make([]dumpEntry, 0)
+	for _, e := range entries {
+		if filter.selects(e) {
+			selected = append(selected, e)
+		}
+	}
+	fmt.Printf("%v of the %v entries of %v selected\n", len(selected), len(entries), *dumpFlag)
+	if len(selected) == 0 {
+		return
+	}
+
+	useBackend(backendSpec{System: *systemFlag, Port: *portFlag, User: *userFlag, Pwd: *pwdFlag, DBName: *dbNameFlag})
+	timeout = *timeoutFlag
+	connect(ctx)
+	defer closeDB(context.Background())
+	checkErr(utils.CleanUpDB(ctx, db, -1))
+
+	output := *outputFlag
+	if output == "" {
+		output = strings.TrimSuffix(strings.TrimSuffix(*dumpFlag, ".txt"), "_dump") + "_replay.csv"
+	}
+	file, err := os.Create(output)
+	checkErr(err)
+	defer file.Close()
+	_, err = file.WriteString("entry,graph,recorded system,n,p,recorded,recorded found,replayed,replayed found,same,error\n")
+	checkErr(err)
+
+	replayed, differ := 0, 0
+	loaded := ""
+	for _, e := range selected {
+		if ctx.Err() != nil {
+			fmt.Println("\nInterrupted")
+			break
+		}
+		fmt.Printf("\r[%v]Replaying entry %v (%v of %v)", time.Now().Format("2006-01-02T15:04:05"), e.index, replayed+1, len(selected))
+		script, g, err := replayScript(e, backend, graphDir)
+		queryString := ""
+		if err == nil {
+			queryString, err = replayQuery(e, q, g, backend)
+		}
+		if err == nil && strings.Join(script, "\n") != loaded {
+			loaded = ""
+			if err = utils.SetUpDB(ctx, db, script, e.n); err == nil {
+				loaded = strings.Join(script, "\n")
+			}
+		}
+		res := utils.QueryResult{QExecTime: -5, Err: err}
+		if err == nil {
+			res = runAnswered(ctx, answer, queryString)
+		}
+		if res.QExecTime == -4 {
+			fmt.Println("\nInterrupted")
+			break
+		}
+		replayed++
+		same := sameOutcome(e, res)
+		if !same {
+			differ++
+		}
+		recordedFound := strconv.FormatBool(e.result.Found)
+		if !answerRecorded(e) {
+			recordedFound = "unknown"
+		}
+		errMessage := ""
+		if res.Err != nil {
+			errMessage = res.Err.Error()
+		}
+		_, err = file.WriteString(fmt.Sprintf("%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n", e.index, e.graphID, e.system, e.n, e.p,
+			outcome(e.result), recordedFound, outcome(res), res.Found, same, csvQuote(errMessage)))
+		checkErr(err)
+	}
+	fmt.Printf("\nReplayed %v entries on %v : %v with the recorded outcome, %v differing. Written to %v\n", replayed, backend, replayed-differ, differ, output)
+}
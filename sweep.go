@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+// The values taken by one generator parameter (n or p) during a test suite, either :
+//   - an explicit list of values
+//   - a linear range from From to To (included) by Step
+//   - a geometric range from From to To, multiplying by Factor at each step
+//   - Points values from From to To evenly spaced on a log scale
+//
+// Linear and geometric ranges are computed on exact decimals, so 0.1 to 1.0 by 0.1 gives exactly 10 values.
+type sweepSpec struct {
+	Values []json.Number `json:"values,omitempty"`
+	From   json.Number   `json:"from,omitempty"`
+	To     json.Number   `json:"to,omitempty"`
+	Step   json.Number   `json:"step,omitempty"`
+	Factor json.Number   `json:"factor,omitempty"`
+	Points int           `json:"points,omitempty"`
+}
+
+// A sweep can also be written as a string, as in the -n and -p flags :
+// "10,20,50" (list), "10:300:10" (linear), "10:1000:x2" (geometric) or "10:1000:log5" (log scale)
+func parseSweep(s string) (sweepSpec, error) {
+	if !strings.Contains(s, ":") {
+		values := make([]json.Number, 0)
+		for _, v := range strings.Split(s, ",") {
+			values = append(values, json.Number(strings.TrimSpace(v)))
+		}
+		return sweepSpec{Values: values}, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return sweepSpec{}, fmt.Errorf("invalid sweep %q, expected from:to:step, from:to:xFactor or from:to:logPoints", s)
+	}
+	sweep := sweepSpec{From: json.Number(parts[0]), To: json.Number(parts[1])}
+	switch {
+	case strings.HasPrefix(parts[2], "x"):
+		sweep.Factor = json.Number(strings.TrimPrefix(parts[2], "x"))
+	case strings.HasPrefix(parts[2], "log"):
+		points, err := strconv.Atoi(strings.TrimPrefix(parts[2], "log"))
+		if err != nil {
+			return sweepSpec{}, fmt.Errorf("invalid number of points in sweep %q", s)
+		}
+		sweep.Points = points
+	default:
+		sweep.Step = json.Number(parts[2])
+	}
+	return sweep, nil
+}
+
+func (s *sweepSpec) UnmarshalJSON(data []byte) error {
+	var compact string
+	if json.Unmarshal(data, &compact) == nil {
+		sweep, err := parseSweep(compact)
+		*s = sweep
+		return err
+	}
+	type plainSweep sweepSpec
+	return decodeStrict(data, (*plainSweep)(s))
+}
+
+func linearSweep(from string, to string, step string) sweepSpec {
+	return sweepSpec{From: json.Number(from), To: json.Number(to), Step: json.Number(step)}
+}
+
+func parseDecimal(n json.Number) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(string(n))
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number", n)
+	}
+	return r, nil
+}
+
+// Returns the values of the sweep
+func (s sweepSpec) values() ([]float64, error) {
+	if len(s.Values) > 0 {
+		values := make([]float64, 0)
+		for _, v := range s.Values {
+			r, err := parseDecimal(v)
+			if err != nil {
+				return nil, err
+			}
+			f, _ := r.Float64()
+			values = append(values, f)
+		}
+		return values, nil
+	}
+
+	from, err := parseDecimal(s.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseDecimal(s.To)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, 0)
+	switch {
+	case s.Points > 0:
+		fromF, _ := from.Float64()
+		toF, _ := to.Float64()
+		if fromF <= 0 || toF <= 0 {
+			return nil, errors.New("log scale sweeps need positive bounds")
+		}
+		values = append(values, fromF)
+		for i := 1; i < s.Points-1; i++ {
+			exponent := math.Log(fromF) + float64(i)*(math.Log(toF)-math.Log(fromF))/float64(s.Points-1)
+			values = append(values, math.Exp(exponent))
+		}
+		if s.Points > 1 {
+			values = append(values, toF)
+		}
+	case s.Factor != "":
+		factor, err := parseDecimal(s.Factor)
+		if err != nil {
+			return nil, err
+		}
+		if from.Sign() <= 0 || factor.Cmp(big.NewRat(1, 1)) <= 0 {
+			return nil, errors.New("geometric sweeps need a positive start and a factor greater than 1")
+		}
+		for v := from; v.Cmp(to) <= 0; v = new(big.Rat).Mul(v, factor) {
+			f, _ := v.Float64()
+			values = append(values, f)
+		}
+	default:
+		step, err := parseDecimal(s.Step)
+		if err != nil {
+			return nil, err
+		}
+		if step.Sign() <= 0 {
+			return nil, errors.New("linear sweeps need a positive step")
+		}
+		for v := from; v.Cmp(to) <= 0; v = new(big.Rat).Add(v, step) {
+			f, _ := v.Float64()
+			values = append(values, f)
+		}
+	}
+	if len(values) == 0 { // a range whose start is past its end
+		return nil, fmt.Errorf("the sweep from %v to %v is empty", s.From, s.To)
+	}
+	return values, nil
+}
+
+// Returns the values of a sweep of graph sizes, rounded to the nearest integer and without duplicates
+func (s sweepSpec) sizes() ([]int, error) {
+	values, err := s.values()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, v := range values {
+		n := int(math.Round(v))
+		if n < 1 {
+			return nil, fmt.Errorf("graphs must have at least one node, got %v", v)
+		}
+		if !seen[n] {
+			seen[n] = true
+			sizes = append(sizes, n)
+		}
+	}
+	return sizes, nil
+}
+
+// A configuration of the graph generators
+type cell struct {
+	n int
+	p float64 // -1 for families that do not depend on an edge probability
+}
+
+// Returns every combination of the values of the n and p sweeps, p varying slowest.
+// p is left out for graph families that do not depend on it.
+func sweepCells(family utils.GraphFamily, sweeps map[string]sweepSpec) ([]cell, error) {
+	for param := range sweeps {
+		if param != "n" && param != "p" {
+			return nil, fmt.Errorf("unknown generator parameter %q, expected n or p", param)
+		}
+	}
+	sizes, err := sweeps["n"].sizes()
+	if err != nil {
+		return nil, fmt.Errorf("n : %w", err)
+	}
+	probabilities, err := sweepProbabilities(family, sweeps)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]cell, 0)
+	for _, p := range probabilities {
+		for _, n := range sizes {
+			cells = append(cells, cell{n: n, p: p})
+		}
+	}
+	return cells, nil
+}
+
+// Returns the values of the p sweep, or only -1 for graph families that do not depend on it
+func sweepProbabilities(family utils.GraphFamily, sweeps map[string]sweepSpec) ([]float64, error) {
+	if !family.Probabilistic() {
+		return []float64{-1}, nil
+	}
+	probabilities, err := sweeps["p"].values()
+	if err != nil {
+		return nil, fmt.Errorf("p : %w", err)
+	}
+	return probabilities, nil
+}
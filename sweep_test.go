@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Arogova/neo4j_performance_test/utils"
+)
+
+func TestSweepCells(t *testing.T) {
+	tests := []struct {
+		name   string
+		family utils.GraphFamily
+		n, p   string
+		cells  []cell
+	}{
+		{"list", utils.RandomGraph, "10, 20,50", "0.5", []cell{{10, 0.5}, {20, 0.5}, {50, 0.5}}},
+		{"linear", utils.RandomGraph, "10:30:10", "0.1:0.3:0.1", []cell{{10, 0.1}, {20, 0.1}, {30, 0.1}, {10, 0.2}, {20, 0.2}, {30, 0.2}, {10, 0.3}, {20, 0.3}, {30, 0.3}}},
+		{"linear to a bound off the step", utils.RandomGraph, "10:35:10", "1", []cell{{10, 1}, {20, 1}, {30, 1}}},
+		{"geometric", utils.DoubleLineGraph, "10:100:x2", "", []cell{{10, -1}, {20, -1}, {40, -1}, {80, -1}}},
+		{"log scale", utils.DoubleLineGraph, "10:1000:log5", "", []cell{{10, -1}, {32, -1}, {100, -1}, {316, -1}, {1000, -1}}},
+		{"log scale rounded to distinct sizes", utils.DoubleLineGraph, "1:3:log5", "", []cell{{1, -1}, {2, -1}, {3, -1}}},
+		{"single point", utils.DoubleLineGraph, "10:1000:log1", "", []cell{{10, -1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sweeps := make(map[string]sweepSpec)
+			var err error
+			sweeps["n"], err = parseSweep(test.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.p != "" {
+				if sweeps["p"], err = parseSweep(test.p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cells, err := sweepCells(test.family, sweeps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cells, test.cells) {
+				t.Errorf("got %v, want %v", cells, test.cells)
+			}
+		})
+	}
+}
+
+func TestSweepProbabilitiesAreExact(t *testing.T) {
+	sweep, err := parseSweep("0.1:1.0:0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := sweep.values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 10 || values[2] != 0.3 || values[9] != 1.0 {
+		t.Errorf("got %v, want 0.1 to 1.0 by exactly 0.1", values)
+	}
+}
+
+func TestInvalidSweeps(t *testing.T) {
+	for _, s := range []string{"10:20", "10:20:logx", "100:10:10", "100:10:x2", "10:100:x1", "10:100:0", "0:100:log3", "a:b:1", "10,x"} {
+		t.Run(s, func(t *testing.T) {
+			sweep, err := parseSweep(s)
+			if err == nil {
+				_, err = sweep.sizes()
+			}
+			if err == nil {
+				t.Errorf("%q was accepted", s)
+			}
+		})
+	}
+}
+
+func TestSweepJSON(t *testing.T) {
+	tests := []struct {
+		json  string
+		sizes []int
+	}{
+		{`{"values": [10, 20, 50]}`, []int{10, 20, 50}},
+		{`{"from": 10, "to": 30, "step": 10}`, []int{10, 20, 30}},
+		{`{"from": 10, "to": 100, "factor": 2}`, []int{10, 20, 40, 80}},
+		{`{"from": 10, "to": 1000, "points": 3}`, []int{10, 100, 1000}},
+		{`"10:30:10"`, []int{10, 20, 30}},
+	}
+	for _, test := range tests {
+		var sweep sweepSpec
+		if err := json.Unmarshal([]byte(test.json), &sweep); err != nil {
+			t.Fatalf("%v : %v", test.json, err)
+		}
+		sizes, err := sweep.sizes()
+		if err != nil {
+			t.Fatalf("%v : %v", test.json, err)
+		}
+		if !reflect.DeepEqual(sizes, test.sizes) {
+			t.Errorf("%v : got %v, want %v", test.json, sizes, test.sizes)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The systems a query can be run on
+type Backend string
+
+const (
+	Neo4j    Backend = "neo4j"
+	Memgraph Backend = "memgraph"
+	Postgres Backend = "postgres"
+	DuckDB   Backend = "duckDB"
+)
+
+// The random graph families a query can be run on
+type GraphFamily string
+
+const (
+	RandomGraph     GraphFamily = "random"
+	LabeledGraph    GraphFamily = "labeled"
+	DoubleLineGraph GraphFamily = "doubleLine"
+	EdgeValueGraph  GraphFamily = "edgeValue"
+	NodeValueGraph  GraphFamily = "nodeValue"
+)
+
+var GraphFamilies = []GraphFamily{RandomGraph, LabeledGraph, DoubleLineGraph, EdgeValueGraph, NodeValueGraph}
+
+// How the result of a query is turned into a yes/no answer
+type Answer int
+
+const (
+	AnyRow        Answer = iota // yes iff the query returns at least one row
+	BoolValue                   // yes iff the first column of the first row is true
+	PositiveCount               // yes iff the first column of the first row is a positive number
+)
+
+// Returns a graph with n nodes of some family, whose start and end nodes, if any, are picked with endpoints.
+// Families that are not random (see Probabilistic) ignore p.
+type GraphGenerator func(n int, p float64, endpoints EndpointStrategy) *Graph
+
+var graphGenerators = map[GraphFamily]map[Backend]GraphGenerator{
+	RandomGraph: {
+		Neo4j:    GenerateRandomGraph,
+		Memgraph: GenerateRandomGraph,
+		Postgres: GenerateRandomUndirectedGraph,
+		DuckDB:   GenerateRandomUndirectedGraph,
+	},
+	LabeledGraph: {
+		Neo4j:    GenerateLabeledGraph,
+		Memgraph: GenerateLabeledGraph,
+		Postgres: GenerateLabeledGraph,
+		DuckDB:   GenerateLabeledGraph,
+	},
+	DoubleLineGraph: {
+		Neo4j:    GenerateDoubleLineGraph,
+		Memgraph: GenerateDoubleLineGraph,
+		Postgres: GenerateDoubleLineGraph,
+		DuckDB:   GenerateDoubleLineGraph,
+	},
+	EdgeValueGraph: {
+		Neo4j:    GenerateEdgeValueGraph,
+		Memgraph: GenerateEdgeValueGraph,
+	},
+	NodeValueGraph: {
+		Neo4j:    GenerateNodeValueGraph,
+		Memgraph: GenerateNodeValueGraph,
+	},
+}
+
+var graphScripts = map[Backend]func(g *Graph) []string{
+	Neo4j:    CypherScript,
+	Memgraph: CypherScript,
+	Postgres: PostgresScript,
+	DuckDB:   DuckDBScript,
+}
+
+// Returns the generator for this family on backend b, if there is one
+func (f GraphFamily) Generator(b Backend) (GraphGenerator, bool) {
+	gen, ok := graphGenerators[f][b]
+	return gen, ok
+}
+
+// Returns the queries creating g on backend b
+func GraphScript(b Backend, g *Graph) []string {
+	return graphScripts[b](g)
+}
+
+// Whether graphs of this family depend on an edge probability
+func (f GraphFamily) Probabilistic() bool {
+	return f != DoubleLineGraph
+}
+
+// Returns the text of a query to run on a graph of n nodes,
+// given the nodes drawn for each of the query's endpoints
+type Renderer func(n int, nodes []int) (string, error)
+
+// The randomized parameters of a query, in the order of Query.Parameters
+type QueryParams []int
+
+// A query of the test suite, along with everything needed to run it
+type Query interface {
+	Name() string
+	Description() string
+	Graph() GraphFamily
+	// The names of the randomized parameters of the query
+	Parameters() []string
+	// Returns the text of the query for backend b on graph g, with nodes picked using endpoints,
+	// along with the randomized parameters it was built with
+	Render(b Backend, g *Graph, endpoints EndpointStrategy) (string, QueryParams, error)
+	// Returns the text of the query for backend b on graph g, with the given nodes
+	RenderNodes(b Backend, g *Graph, nodes QueryParams) (string, error)
+	Supports(b Backend) bool
+	Answer() Answer
+	Complexity() string
+}
+
+type catalogQuery struct {
+	name        string
+	description string
+	graph       GraphFamily
+	endpoints   []string // names of the nodes picked for each query, by source/target pairs
+	randomNodes int      // how many nodes are drawn uniformly for each query, recorded after the endpoints
+	renderers   map[Backend]Renderer
+	answer      Answer
+	complexity  string
+}
+
+func (q catalogQuery) Name() string        { return q.name }
+func (q catalogQuery) Description() string { return q.description }
+func (q catalogQuery) Graph() GraphFamily  { return q.graph }
+func (q catalogQuery) Answer() Answer      { return q.answer }
+func (q catalogQuery) Complexity() string  { return q.complexity }
+
+func (q catalogQuery) Parameters() []string {
+	parameters := append([]string{}, q.endpoints...)
+	for i := 1; i <= q.randomNodes; i++ {
+		parameters = append(parameters, fmt.Sprintf("random%v", i))
+	}
+	return parameters
+}
+
+func (q catalogQuery) Supports(b Backend) bool {
+	_, hasRenderer := q.renderers[b]
+	_, hasGenerator := q.graph.Generator(b)
+	return hasRenderer && hasGenerator
+}
+
+func (q catalogQuery) Render(b Backend, g *Graph, endpoints EndpointStrategy) (string, QueryParams, error) {
+	nodes := QueryParams(endpoints.Pick(g, len(q.endpoints)/2))
+	for i := 0; i < q.randomNodes; i++ {
+		nodes = append(nodes, g.randomNode())
+	}
+	query, err := q.RenderNodes(b, g, nodes)
+	return query, nodes, err
+}
+
+func (q catalogQuery) RenderNodes(b Backend, g *Graph, nodes QueryParams) (string, error) {
+	if !q.Supports(b) {
+		return "", fmt.Errorf("%v is not implemented for %v", q.name, b)
+	}
+	return q.renderers[b](g.Nodes, nodes)
+}
+
+func sizeless(query func() string) Renderer {
+	return func(n int, nodes []int) (string, error) { return query(), nil }
+}
+
+func sized(query func(n int) string) Renderer {
+	return func(n int, nodes []int) (string, error) { return query(n), nil }
+}
+
+func onePair(query func(source int, target int) string) Renderer {
+	return func(n int, nodes []int) (string, error) { return query(nodes[0], nodes[1]), nil }
+}
+
+func twoPairs(query func(s1 int, t1 int, s2 int, t2 int) string) Renderer {
+	return func(n int, nodes []int) (string, error) { return query(nodes[0], nodes[1], nodes[2], nodes[3]), nil }
+}
+
+// DuckDB times queries on the client side and reads the actual rows,
+// so SQL queries are run without the explain analyze used for postgres
+func withoutExplain(query Renderer) Renderer {
+	return func(n int, nodes []int) (string, error) {
+		q, err := query(n, nodes)
+		return strings.TrimPrefix(q, "explain analyze "), err
+	}
+}
+
+var sourceTarget = []string{"source", "target"}
+var twoSourceTargets = []string{"source1", "target1", "source2", "target2"}
+
+// Renderers for a query written in Cypher and understood by both neo4j and memgraph
+func cypher(query Renderer) map[Backend]Renderer {
+	return map[Backend]Renderer{Neo4j: query, Memgraph: query}
+}
+
+var Catalog = []Query{
+	catalogQuery{
+		name:        "tdp",
+		description: "two disjoint paths",
+		graph:       RandomGraph,
+		endpoints:   twoSourceTargets,
+		renderers:   cypher(twoPairs(TwoDisjointPathQuery)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "hamil",
+		description: "hamiltonian path",
+		graph:       RandomGraph,
+		renderers: map[Backend]Renderer{
+			Neo4j:    sizeless(HamiltonianPath),
+			Memgraph: sizeless(HamiltonianPathMemgraph),
+			Postgres: sizeless(HamiltonianSQL),
+			DuckDB:   withoutExplain(sizeless(HamiltonianSQL)),
+		},
+		answer:     AnyRow,
+		complexity: "NP-complete",
+	},
+	catalogQuery{
+		name:        "enum",
+		description: "trail enumeration",
+		graph:       RandomGraph,
+		endpoints:   sourceTarget,
+		renderers:   cypher(onePair(EnumeratePaths)),
+		answer:      PositiveCount,
+		complexity:  "#P-complete",
+	},
+	catalogQuery{
+		name:        "any",
+		description: "any path",
+		graph:       RandomGraph,
+		endpoints:   sourceTarget,
+		renderers:   cypher(onePair(FindAnyPath)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "tgfree",
+		description: "triangle free",
+		graph:       RandomGraph,
+		renderers:   cypher(sizeless(TriangleFree)),
+		answer:      BoolValue,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "euler",
+		description: "eulerian trail",
+		graph:       RandomGraph,
+		renderers: map[Backend]Renderer{
+			Neo4j:    sizeless(EulerianTrail),
+			Memgraph: sizeless(EulerianTrailMemgraph),
+			Postgres: sizeless(EulerianSQL),
+			DuckDB:   withoutExplain(sizeless(EulerianSQL)),
+		},
+		answer:     AnyRow,
+		complexity: "polynomial",
+	},
+	catalogQuery{
+		name:        "NormalAStarBStar",
+		description: "a*b*, the old fashioned way",
+		graph:       LabeledGraph,
+		renderers:   cypher(sizeless(NormalAStarBStar)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "AutomataAStarBStar",
+		description: "a*b*, the automata way",
+		graph:       LabeledGraph,
+		renderers:   cypher(sizeless(AutomataAStarBStar)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "SmartTDP",
+		description: "two disjoint path using Cypher trail semantics",
+		graph:       RandomGraph,
+		endpoints:   twoSourceTargets,
+		renderers:   cypher(twoPairs(SmartTwoDisjointPathQuery)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "ShortestHamil",
+		description: "Shortest path variant of Hamiltonian path",
+		graph:       RandomGraph,
+		renderers:   cypher(sized(ShortestHamiltonian)),
+		answer:      AnyRow,
+		complexity:  "NP-complete",
+	},
+	catalogQuery{
+		name:        "SubsetSum",
+		description: "Subset sum query",
+		graph:       DoubleLineGraph,
+		renderers: map[Backend]Renderer{
+			Neo4j:    sized(SubsetSum),
+			Memgraph: sized(SubsetSum),
+			Postgres: sizeless(SubsetSumSQL),
+			DuckDB:   withoutExplain(sizeless(SubsetSumSQL)),
+		},
+		answer:     AnyRow,
+		complexity: "NP-complete",
+	},
+	catalogQuery{
+		name:        "AStarBAStar",
+		description: "a*ba*",
+		graph:       LabeledGraph,
+		renderers: map[Backend]Renderer{
+			Neo4j:    sizeless(AStarBAStar),
+			Memgraph: sizeless(AStarBAStar),
+			Postgres: sizeless(AStarBAStarSQL),
+			DuckDB:   withoutExplain(sizeless(AStarBAStarDuckDB)),
+		},
+		answer:     AnyRow,
+		complexity: "NP-complete",
+	},
+	catalogQuery{
+		name:        "IncreasingPath",
+		description: "Value increasing along the edges",
+		graph:       EdgeValueGraph,
+		renderers:   cypher(sizeless(IncreasingPath)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+	catalogQuery{
+		name:        "IncreasingNode",
+		description: "Value increasing along the nodes",
+		graph:       NodeValueGraph,
+		renderers:   cypher(sizeless(IncreasingPathNode)),
+		answer:      AnyRow,
+		complexity:  "polynomial",
+	},
+}
+
+// Returns the query of the catalog with the given name
+func FindQuery(name string) (Query, bool) {
+	for _, q := range Catalog {
+		if q.Name() == name {
+			return q, true
+		}
+	}
+	return nil, false
+}
+
+// Returns a human readable list of the queries of the catalog
+func CatalogDescription() string {
+	desc := "Available queries are :"
+	for _, q := range Catalog {
+		backends := make([]string, 0)
+		for _, b := range []Backend{Neo4j, Memgraph, Postgres, DuckDB} {
+			if q.Supports(b) {
+				backends = append(backends, string(b))
+			}
+		}
+		desc += fmt.Sprintf("\n'%v' : %v (%v graph, %v, on %v)", q.Name(), q.Description(), q.Graph(), q.Complexity(), strings.Join(backends, ", "))
+	}
+	return desc
+}
+
+// Adds queries to the catalog, refusing names that are already taken
+func RegisterQueries(queries []Query) error {
+	for _, q := range queries {
+		if _, found := FindQuery(q.Name()); found {
+			return fmt.Errorf("a query named %v already exists", q.Name())
+		}
+		Catalog = append(Catalog, q)
+	}
+	return nil
+}
@@ -3,153 +3,236 @@ package utils
 import (
 	"fmt"
 	"math/rand"
+	"time"
 )
 
+// The random number generator behind every random choice of the package
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Makes the following random choices of the package depend only on seed
+func SetSeed(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// Returns a random int between 0 and n-1
+func RandomIndex(n int) int {
+	return rng.Intn(n)
+}
+
 // Returns a *possibly negative* int between -n and n
 func getRandomInteger(n int) int {
-	randInt := rand.Intn(n)
-	if rand.Float64() < 0.5 {
+	randInt := rng.Intn(n)
+	if rng.Float64() < 0.5 {
 		return randInt
 	} else {
 		return randInt * -1
 	}
 }
 
-//Cypher
+func newGraph(family GraphFamily, n int) *Graph {
+	return &Graph{Family: family, Nodes: n, Edges: make([]Edge, 0), Start: -1, End: -1}
+}
 
-// Returns a neo4j query that creates a random graph of n nodes such that
-// each pair of nodes is linked with probability p
-func CreateRandomGraphScript(n int, p float64) []string {
-	query := make([]string, 0)
-	for i := 0; i < n; i++ {
-		query = append(query, fmt.Sprintf("CREATE ({name:%d})", i))
-	}
+// Labels the start and end nodes of g, chosen with the given strategy
+func (g *Graph) pickStartEnd(endpoints EndpointStrategy) {
+	nodes := endpoints.Pick(g, 1)
+	g.Start, g.End = nodes[0], nodes[1]
+}
+
+//Generators
+
+// Returns a random graph of n nodes such that each (ordered) pair of nodes
+// is linked with probability p
+func GenerateRandomGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(RandomGraph, n)
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
-			if rand.Float64() <= p {
-				edgeQuery := fmt.Sprintf("MATCH (v1{name:%d}) MATCH (v2{name:%d}) CREATE (v1)-[:Edge]->(v2)", i, j)
-				query = append(query, edgeQuery)
+			if rng.Float64() <= p {
+				g.Edges = append(g.Edges, Edge{Src: i, Trg: j, Label: "Edge"})
 			}
 		}
 	}
-	rand_start_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :Start", rand_start_node))
-	rand_end_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :End", rand_end_node))
-	return query
+	g.pickStartEnd(endpoints)
+	return g
 }
 
-func CreateRandomDoubleLineGraphScript(n int) []string {
-	query := make([]string, 0)
-	
-	query = append(query, `CREATE (:Start {name:"0"})`)
-	for i := 1; i < n-1; i++ {
-		query = append(query, fmt.Sprintf(`CREATE ({name:"%d"})`, i))
+//Note the representation of the undirected graph : for every undirected edge, we include both corresponding directed edges.
+
+// Returns a random undirected graph of n nodes such that each (unordered) pair of nodes
+// is linked with probability p. This is the random graph used by the SQL queries.
+func GenerateRandomUndirectedGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(RandomGraph, n)
+	g.Undirected = true
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			if rng.Float64() <= p {
+				g.Edges = append(g.Edges, Edge{Src: i, Trg: j, Label: "Edge"})
+				if i != j {
+					g.Edges = append(g.Edges, Edge{Src: j, Trg: i, Label: "Edge"})
+				}
+			}
+		}
 	}
-	query = append(query, fmt.Sprintf(`CREATE (:End {name:"%d"})`, n-1))
+	g.pickStartEnd(endpoints)
+	return g
+}
+
+// Returns a line of n nodes from Start to End, where each node is linked to the next one by two edges :
+// one with value 0 and one with a random value. The first two edges have value 1 and a random value.
+func GenerateDoubleLineGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(DoubleLineGraph, n)
+	g.Start, g.End = 0, n-1
 
-	
-	query = append(query, `MATCH (v1{name:"0"}) MATCH (v2{name:"1"}) CREATE (v1)-[:Edge {value:1}]->(v2)`)
-	query = append(query, fmt.Sprintf(`MATCH (v1{name:"0"}) MATCH (v2{name:"1"}) CREATE (v1)-[:Edge {value:%d}]->(v2)`, getRandomInteger(10)))
+	g.Edges = append(g.Edges, Edge{Src: 0, Trg: 1, Label: "Edge", Value: 1})
+	g.Edges = append(g.Edges, Edge{Src: 0, Trg: 1, Label: "Edge", Value: getRandomInteger(10)})
 
 	for i := 1; i < n-1; i++ {
-		query = append(query, fmt.Sprintf(`MATCH (v1{name:"%d"}) MATCH (v2{name:"%d"}) CREATE (v1)-[:Edge {value:0}]->(v2)`, i, i+1))
-		query = append(query, fmt.Sprintf(`MATCH (v1{name:"%d"}) MATCH (v2{name:"%d"}) CREATE (v1)-[:Edge {value:%d}]->(v2)`, i, i+1, getRandomInteger(10)))
+		g.Edges = append(g.Edges, Edge{Src: i, Trg: i + 1, Label: "Edge", Value: 0})
+		g.Edges = append(g.Edges, Edge{Src: i, Trg: i + 1, Label: "Edge", Value: getRandomInteger(10)})
 	}
-
-	return query
+	return g
 }
 
-func CreateLabeledGraphScript(n int, p float64) []string {
-	query := make([]string, 0)
-	for i := 0; i < n; i++ {
-		query = append(query, fmt.Sprintf("CREATE ({name:%d})", i))
-	}
+// Returns a random graph whose edges are labeled a or b with the same probability
+func GenerateLabeledGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(LabeledGraph, n)
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
 			label := "a"
-			if rand.Float64() < 0.5 {
+			if rng.Float64() < 0.5 {
 				label = "b"
 			}
-			if rand.Float64() <= p {
-				edgeQuery := fmt.Sprintf("MATCH (v1{name:%d}) MATCH (v2{name:%d}) CREATE (v1)-[:%v]->(v2)", i, j, label)
-				query = append(query, edgeQuery)
+			if rng.Float64() <= p {
+				g.Edges = append(g.Edges, Edge{Src: i, Trg: j, Label: label})
 			}
 		}
 	}
-
-	rand_start_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :Start", rand_start_node))
-	rand_end_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :End", rand_end_node))
-	return query
+	g.pickStartEnd(endpoints)
+	return g
 }
 
-func CreateEdgeValueGraphScript(n int, p float64) []string {
-	query := make([]string, 0)
-	for i := 0; i < n; i++ {
-		query = append(query, fmt.Sprintf("CREATE ({name:%d})", i))
-	}
+// Returns a random graph whose edges have a random value between 0 and 99
+func GenerateEdgeValueGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(EdgeValueGraph, n)
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
-			if rand.Float64() <= p {
-				value := rand.Intn(100)
-				edgeQuery := fmt.Sprintf("MATCH (v1{name:%d}) MATCH (v2{name:%d}) CREATE (v1)-[:Edge {val:%d}]->(v2)", i, j, value)
-				query = append(query, edgeQuery)
+			if rng.Float64() <= p {
+				g.Edges = append(g.Edges, Edge{Src: i, Trg: j, Label: "Edge", Value: rng.Intn(100)})
 			}
 		}
 	}
-	return query
+	return g
 }
 
-func CreateNodeValueGraphScript(n int, p float64) []string {
-	query := make([]string, 0)
+// Returns a random graph whose nodes have a random value between 0 and 99
+func GenerateNodeValueGraph(n int, p float64, endpoints EndpointStrategy) *Graph {
+	g := newGraph(NodeValueGraph, n)
+	g.Values = make([]int, n)
 	for i := 0; i < n; i++ {
-		query = append(query, fmt.Sprintf("CREATE ({name:%d, val:%d})", i, rand.Intn(100)))
+		g.Values[i] = rng.Intn(100)
 	}
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
-			if rand.Float64() <= p {
-				edgeQuery := fmt.Sprintf("MATCH (v1{name:%d}) MATCH (v2{name:%d}) CREATE (v1)-[:Edge]->(v2)", i, j)
-				query = append(query, edgeQuery)
+			if rng.Float64() <= p {
+				g.Edges = append(g.Edges, Edge{Src: i, Trg: j, Label: "Edge"})
 			}
 		}
 	}
+	return g
+}
+
+//Cypher
+
+// Returns a neo4j query that creates g
+func CypherScript(g *Graph) []string {
+	query := make([]string, 0)
+	for i := 0; i < g.Nodes; i++ {
+		if g.Values != nil {
+			query = append(query, fmt.Sprintf("CREATE ({name:%d, val:%d})", i, g.Values[i]))
+		} else {
+			query = append(query, fmt.Sprintf("CREATE ({name:%d})", i))
+		}
+	}
+	for _, e := range g.Edges {
+		properties := ""
+		switch g.Family {
+		case DoubleLineGraph:
+			properties = fmt.Sprintf(" {value:%d}", e.Value)
+		case EdgeValueGraph:
+			properties = fmt.Sprintf(" {val:%d}", e.Value)
+		}
+		query = append(query, fmt.Sprintf("MATCH (v1{name:%d}) MATCH (v2{name:%d}) CREATE (v1)-[:%v%v]->(v2)", e.Src, e.Trg, e.Label, properties))
+	}
+	if g.Start != -1 {
+		query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :Start", g.Start))
+	}
+	if g.End != -1 {
+		query = append(query, fmt.Sprintf("MATCH (n {name:%d}) SET n :End", g.End))
+	}
 	return query
 }
 
 // SQL
 
-//Note the representation of the undirected graph : for every undirected edge, we include both corresponding directed edges.
+//Every SQL graph also has a node table V, so that queries can see isolated nodes and the start/end nodes.
 
-func CreateRandomGraphScriptSQL(n int, p float64) []string {
+// Returns the queries that create the node table V of g
+func createNodeTableSQL(g *Graph) []string {
+	query := make([]string, 0)
+	query = append(query, "DROP TABLE IF EXISTS V;")
+	query = append(query, "CREATE TABLE V(id int primary key, label text, val int, is_start boolean, is_end boolean);")
+	for i := 0; i < g.Nodes; i++ {
+		val := "NULL"
+		if g.Values != nil {
+			val = fmt.Sprint(g.Values[i])
+		}
+		query = append(query, fmt.Sprintf("INSERT INTO V VALUES (%d, NULL, %v, %t, %t);", i, val, i == g.Start, i == g.End))
+	}
+	return query
+}
+
+// Returns a postgres query that creates g
+func PostgresScript(g *Graph) []string {
+	return sqlScript(g, false)
+}
+
+// Returns a duckDB query that creates g
+func DuckDBScript(g *Graph) []string {
+	return sqlScript(g, true)
+}
+
+func sqlScript(g *Graph, duckDB bool) []string {
+	switch g.Family {
+	case DoubleLineGraph:
+		return doubleLineGraphScriptSQL(g)
+	case LabeledGraph:
+		return labeledGraphScriptSQL(g, duckDB)
+	default:
+		return randomGraphScriptSQL(g)
+	}
+}
+
+func randomGraphScriptSQL(g *Graph) []string {
 	query := make([]string, 0)
 	query = append(query, "DROP TABLE IF EXISTS G;")
 	query = append(query, "CREATE TABLE G(src int, trg int, primary key(src,trg));")
-
-	for i := 0; i < n; i++ {
-		for j := i; j < n; j++ {
-			if rand.Float64() <= p {
-				query = append(query, fmt.Sprintf("INSERT INTO G VALUES (%d, %d);", i, j))
-				if i != j {
-					query = append(query, fmt.Sprintf("INSERT INTO G VALUES (%d, %d);", j, i))
-				}
-			}
-		}
+	for _, e := range g.Edges {
+		query = append(query, fmt.Sprintf("INSERT INTO G VALUES (%d, %d);", e.Src, e.Trg))
 	}
+	query = append(query, createNodeTableSQL(g)...)
 	return query
 }
 
-func CreateRandomDoubleLineGraphScriptSQL(n int) []string {
+func doubleLineGraphScriptSQL(g *Graph) []string {
 	query := "DROP TABLE IF EXISTS G;"
 	query += "CREATE TABLE G(src int, trg int, weight int);"
 
-	query += "INSERT INTO G VALUES (0, 1, 1);"
-	query += fmt.Sprintf("INSERT INTO G VALUES (0, 1, %d);", getRandomInteger(10))
+	for _, e := range g.Edges {
+		query += fmt.Sprintf("INSERT INTO G VALUES (%d, %d, %d);", e.Src, e.Trg, e.Value)
+	}
 
-	for i := 1; i < n-1; i++ {
-		query += fmt.Sprintf("INSERT INTO G VALUES (%d, %d, 0);", i, i+1)
-		query += fmt.Sprintf("INSERT INTO G VALUES (%d, %d, %d);", i, i+1, getRandomInteger(10))
+	for _, subQuery := range createNodeTableSQL(g) {
+		query += subQuery
 	}
 
 	queryWrapper := make([]string, 0)
@@ -157,66 +240,27 @@ func CreateRandomDoubleLineGraphScriptSQL(n int) []string {
 	return queryWrapper
 }
 
-func CreateLabeledGraphScriptSQL(n int, p float64) []string {
+func labeledGraphScriptSQL(g *Graph, duckDB bool) []string {
 	query := make([]string, 0)
 	query = append(query, "DROP TABLE IF EXISTS A;")
 	query = append(query, "DROP TABLE IF EXISTS B;")
-	query = append(query, "DROP TABLE IF EXISTS StartLabel;")
-	query = append(query, "DROP TABLE IF EXISTS EndLabel;")
-	query = append(query, "CREATE TABLE A (id serial, s int, t int, primary key(s,t));")
-	query = append(query, "CREATE TABLE B (id serial, s int, t int, primary key(s,t));")
-	query = append(query, "CREATE TABLE StartLabel (node int);")
-	query = append(query, "CREATE TABLE EndLabel (node int);")
-
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			if rand.Float64() <= p {
-				if rand.Float64() < 0.5 {
-					query = append(query, fmt.Sprintf("INSERT INTO A (s, t) VALUES (%d, %d);", i, j))
-				} else {
-					query = append(query, fmt.Sprintf("INSERT INTO B (s, t) VALUES (%d, %d);", i, j))
-				}
-			}
-		}
+	if duckDB {
+		query = append(query, "CREATE OR REPLACE SEQUENCE serial START 1;")
+		query = append(query, "CREATE TABLE A (id INTEGER DEFAULT nextval('serial'), s int, t int, primary key(s,t));")
+		query = append(query, "CREATE TABLE B (id INTEGER DEFAULT nextval('serial'), s int, t int, primary key(s,t));")
+	} else {
+		query = append(query, "CREATE TABLE A (id serial, s int, t int, primary key(s,t));")
+		query = append(query, "CREATE TABLE B (id serial, s int, t int, primary key(s,t));")
 	}
 
-	rand_start_node := rand.Intn(n)
-	rand_end_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("INSERT INTO StartLabel VALUES (%d)", rand_start_node))
-	query = append(query, fmt.Sprintf("INSERT INTO EndLabel VALUES (%d)", rand_end_node))
-
-	return query
-}
-
-func CreateLabeledGraphScriptDuckDB(n int, p float64) []string {
-	query := make([]string, 0)
-	query = append(query, "DROP TABLE IF EXISTS A;")
-	query = append(query, "DROP TABLE IF EXISTS B;")
-	query = append(query, "DROP TABLE IF EXISTS StartLabel;")
-	query = append(query, "DROP TABLE IF EXISTS EndLabel;")
-	query = append(query, "CREATE OR REPLACE SEQUENCE serial START 1;")
-	query = append(query, "CREATE TABLE A (id INTEGER DEFAULT nextval('serial'), s int, t int, primary key(s,t));")
-	query = append(query, "CREATE TABLE B (id INTEGER DEFAULT nextval('serial'), s int, t int, primary key(s,t));")
-	query = append(query, "CREATE TABLE StartLabel (node int);")
-	query = append(query, "CREATE TABLE EndLabel (node int);")
-
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			if rand.Float64() <= p {
-				if rand.Float64() < 0.5 {
-					query = append(query, fmt.Sprintf("INSERT INTO A (s, t) VALUES (%d, %d);", i, j))
-				} else {
-					query = append(query, fmt.Sprintf("INSERT INTO B (s, t) VALUES (%d, %d);", i, j))
-				}
-			}
+	for _, e := range g.Edges {
+		if e.Label == "a" {
+			query = append(query, fmt.Sprintf("INSERT INTO A (s, t) VALUES (%d, %d);", e.Src, e.Trg))
+		} else {
+			query = append(query, fmt.Sprintf("INSERT INTO B (s, t) VALUES (%d, %d);", e.Src, e.Trg))
 		}
 	}
 
-	rand_start_node := rand.Intn(n)
-	rand_end_node := rand.Intn(n)
-	query = append(query, fmt.Sprintf("INSERT INTO StartLabel VALUES (%d)", rand_start_node))
-	query = append(query, fmt.Sprintf("INSERT INTO EndLabel VALUES (%d)", rand_end_node))
-
+	query = append(query, createNodeTableSQL(g)...)
 	return query
 }
-
@@ -17,199 +17,339 @@ import (
 )
 
 // Executes the query given as argument
-// Sends number of results to channel c
-func ExecuteQuery(ctx context.Context, db interface{}, queryString string, resChan chan QueryResult, memgraph bool) {
+// Sends the execution time and the answer, read from the result as described by answer, to channel c
+// Postgres queries are run with explain analyze, so only AnyRow answers are supported there
+// Queries that fail for another reason than their context are sent with QExecTime -5 and their error
+func ExecuteQuery(ctx context.Context, db interface{}, queryString string, answer Answer, resChan chan QueryResult, memgraph bool) {
 	switch db.(type) {
 	case neo4j.DriverWithContext:
-		executeNeo4jQuery(ctx, db.(neo4j.DriverWithContext), queryString, resChan, memgraph)
+		executeNeo4jQuery(ctx, db.(neo4j.DriverWithContext), queryString, answer, resChan, memgraph)
 	case *pgxpool.Pool:
-		executePostgresQuery(db.(*pgxpool.Pool), queryString, resChan)
+		executePostgresQuery(ctx, db.(*pgxpool.Pool), queryString, resChan)
 	case *sql.DB:
-		executeDuckDBQuery(ctx, db.(*sql.DB), queryString, resChan)
+		executeDuckDBQuery(ctx, db.(*sql.DB), queryString, answer, resChan)
 	default:
-		panic(errors.New("ExecuteQuery : Database type unknown. This should not happen!"))
+		resChan <- QueryResult{QExecTime: -5, Found: false, Err: errors.New("ExecuteQuery : Database type unknown. This should not happen!")}
 	}
 }
 
-func executeNeo4jQuery(ctx context.Context, db neo4j.DriverWithContext, queryString string, resChan chan QueryResult, memgraph bool) {
+func executeNeo4jQuery(ctx context.Context, db neo4j.DriverWithContext, queryString string, answer Answer, resChan chan QueryResult, memgraph bool) {
 	session := db.NewSession(ctx, neo4j.SessionConfig{})
-	defer HandleClose(ctx, session)
+	defer session.Close(context.Background())
 
-	neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+	// The deadline of ctx is also enforced on the server, so that timed out queries do not keep running
+	txConfig := make([]func(*neo4j.TransactionConfig), 0)
+	if deadline, ok := ctx.Deadline(); ok {
+		txConfig = append(txConfig, neo4j.WithTxTimeout(time.Until(deadline)))
+	}
+
+	sent := false
+	_, err := neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		sent = true
 		startTime := time.Now()
 		result, err := tx.Run(ctx, queryString, nil)
-		checkErr(err)
-		records, err := result.Collect(ctx)
+		var records []*neo4j.Record
+		if err == nil {
+			records, err = result.Collect(ctx)
+		}
 		if memgraph && err != nil { //Memgraph throws errors here for some reason...
-			if neo4j.IsNeo4jError(err) { // Timeout error
-				resChan <- QueryResult{QExecTime: -1, Found: false}
-				return 1, nil
-			} else if neo4j.IsConnectivityError(err) { // "Out of memory" error
+			if isOutOfMemory(err) {
 				resChan <- QueryResult{QExecTime: -2, Found: false}
 				return 1, nil
 			}
+			// Other errors are timeouts only if they say so : lost connections, syntax or transient errors are failures, tried again by the retry policy
+		}
+		var summary neo4j.ResultSummary
+		if err == nil {
+			summary, err = result.Consume(ctx)
 		}
 		if err != nil {
-			fmt.Printf("%v", err)
-			resChan <- QueryResult{QExecTime: -1, Found: false}
+			resChan <- stoppedQuery(ctx, err)
+			return 1, nil
+		}
+		endTime := time.Now()
+		totalTime := 0
+		if memgraph {
+			totalTime = int(endTime.Sub(startTime).Milliseconds())
 		} else {
-			summary, err := result.Consume(ctx)
-			checkErr(err)
-			endTime := time.Now()
-			totalTime := 0
-			if memgraph {
-				totalTime = int(endTime.Sub(startTime).Milliseconds())
-			} else {
-				totalTime = int(summary.ResultAvailableAfter().Milliseconds() + summary.ResultConsumedAfter().Milliseconds())
-			}
-			resChan <- QueryResult{QExecTime: totalTime, Found: len(records) == 1}
+			totalTime = int(summary.ResultAvailableAfter().Milliseconds() + summary.ResultConsumedAfter().Milliseconds())
+		}
+		var first interface{}
+		if len(records) > 0 && len(records[0].Values) > 0 {
+			first = records[0].Values[0]
 		}
+		resChan <- QueryResult{QExecTime: totalTime, Found: interpretAnswer(answer, len(records) > 0, first)}
 		return 1, nil
-	})
+	}, txConfig...)
+	if !sent { // The transaction could not even start
+		resChan <- stoppedQuery(ctx, err)
+	}
 }
 
-func executePostgresQuery(db *pgxpool.Pool, queryString string, resChan chan QueryResult) {
-	// timeoutContext, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	// defer cancel()
-
-	rows, err := db.Query(context.Background(), queryString)
-	checkErr(err)
-	// rows, err := db.Query(timeoutContext, queryString)
-	// checkErr(err)
-
+func executePostgresQuery(ctx context.Context, db *pgxpool.Pool, queryString string, resChan chan QueryResult) {
+	rows, err := db.Query(ctx, queryString)
+	if err != nil {
+		resChan <- stoppedQuery(ctx, err)
+		return
+	}
 	result, err := pgx.CollectRows(rows, pgx.RowTo[string])
-
-	// fmt.Printf("result: %v\n", result)
-	// checkErr(err)
-
-	// rows.Next()
-	// var firstRow string
-	// rows.Scan(&firstRow)
-
-	//if pgconn.Timeout(rows.Err()) {
-	if (rows.Err() != nil && strings.Contains(rows.Err().Error(), "timeout")) || pgconn.Timeout(rows.Err()) {
-		resChan <- QueryResult{QExecTime: -1, Found: false}
+	if err == nil && rows.Err() != pgx.ErrNoRows {
+		err = rows.Err()
+	}
+	if err != nil {
+		resChan <- stoppedQuery(ctx, err)
 		return
-	} else if rows.Err() != nil && rows.Err() != pgx.ErrNoRows {
-		checkErr(rows.Err())
 	}
 
-	nbResults, err := strconv.Atoi(strings.Split(strings.Split(strings.Split(result[0], "actual time")[1], "rows=")[1], " ")[0])
-	checkErr(err)
-	totalTime, err := time.ParseDuration(strings.Join(strings.Split(strings.Split(result[len(result)-1], ": ")[1], " "), ""))
-	checkErr(err)
+	nbResults, totalTime, err := parseExplainAnalyze(result)
+	if err != nil {
+		resChan <- QueryResult{QExecTime: -5, Found: false, Err: err}
+		return
+	}
 	resChan <- QueryResult{QExecTime: int(totalTime.Milliseconds()), Found: nbResults > 0}
 }
 
-func executeDuckDBQuery(ctx context.Context, db *sql.DB, queryString string, resChan chan QueryResult) {
+// Returns the number of rows and the execution time reported by the output of explain analyze
+func parseExplainAnalyze(result []string) (int, time.Duration, error) {
+	if len(result) == 0 {
+		return 0, 0, errors.New("explain analyze returned nothing")
+	}
+	plan := strings.Split(result[0], "actual time")
+	if len(plan) < 2 || len(strings.Split(plan[1], "rows=")) < 2 {
+		return 0, 0, fmt.Errorf("no actual rows in the plan %q", result[0])
+	}
+	nbResults, err := strconv.Atoi(strings.Split(strings.Split(plan[1], "rows=")[1], " ")[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	execution := strings.Split(result[len(result)-1], ": ")
+	if len(execution) < 2 {
+		return 0, 0, fmt.Errorf("no execution time in %q", result[len(result)-1])
+	}
+	totalTime, err := time.ParseDuration(strings.Join(strings.Split(execution[1], " "), ""))
+	return nbResults, totalTime, err
+}
+
+// DuckDB queries are timed on the client until their first row is available. Older runs used explain analyze, whose times are not comparable
+func executeDuckDBQuery(ctx context.Context, db *sql.DB, queryString string, answer Answer, resChan chan QueryResult) {
+	if answer == AnyRow { // only whether there is a row matters, and rows may hold values the driver cannot convert, such as arrays of unnamed tuples
+		queryString = fmt.Sprintf("SELECT 1 FROM (\n%v\n) LIMIT 1;", strings.TrimSuffix(strings.TrimSpace(queryString), ";"))
+	}
 	startTime := time.Now()
 	rows, err := db.QueryContext(ctx, queryString)
 	endTime := time.Now()
+	if err != nil {
+		resChan <- stoppedQuery(ctx, err)
+		return
+	}
 	defer rows.Close()
-	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
-		resChan <- QueryResult{QExecTime: -1, Found: false}
+	hasRow := rows.Next()
+	var first interface{}
+	if hasRow {
+		columns, err := rows.Columns()
+		if err != nil {
+			resChan <- stoppedQuery(ctx, err)
+			return
+		}
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			resChan <- stoppedQuery(ctx, err)
+			return
+		}
+		first = *values[0].(*interface{})
+	} else if err := rows.Err(); err != nil {
+		resChan <- stoppedQuery(ctx, err)
 		return
-	} else if err != nil {
-		panic(err)
-	} else {
-		if rows.Next() {
-			resChan <- QueryResult{QExecTime: int(endTime.Sub(startTime).Milliseconds()), Found: true}
-		} else {
-			resChan <- QueryResult{QExecTime: int(endTime.Sub(startTime).Milliseconds()), Found: false}
+	}
+	resChan <- QueryResult{QExecTime: int(endTime.Sub(startTime).Milliseconds()), Found: interpretAnswer(answer, hasRow, first)}
+}
+
+// Returns the result of a query stopped by err : interrupted (-4) if ctx was canceled, timed out (-1) if ctx or the database timed out,
+// and failed (-5) otherwise
+func stoppedQuery(ctx context.Context, err error) QueryResult {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return QueryResult{QExecTime: -4, Found: false}
+	case ctx.Err() != nil || isTimeout(err):
+		return QueryResult{QExecTime: -1, Found: false}
+	default:
+		return QueryResult{QExecTime: -5, Found: false, Err: err}
+	}
+}
+
+// Whether the database reported running out of memory, as memgraph does when a query exceeds its memory limit
+func isOutOfMemory(err error) bool {
+	var neo4jErr *neo4j.Neo4jError
+	return errors.As(err, &neo4jErr) && (strings.Contains(strings.ToLower(neo4jErr.Msg), "memory") || strings.Contains(strings.ToLower(neo4jErr.Code), "memory"))
+}
+
+func isTimeout(err error) bool {
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) && (strings.Contains(neo4jErr.Code, "TransactionTimedOut") || strings.Contains(neo4jErr.Msg, "transaction timeout")) { // memgraph only tells in its message
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) || strings.Contains(err.Error(), "statement timeout")
+}
+
+// Returns the answer of a query given whether it returned any row
+// and the first value of its first row
+func interpretAnswer(answer Answer, hasRow bool, first interface{}) bool {
+	if !hasRow {
+		return false
+	}
+	switch answer {
+	case BoolValue:
+		value, ok := first.(bool)
+		return ok && value
+	case PositiveCount:
+		switch value := first.(type) {
+		case int64:
+			return value > 0
+		case int32:
+			return value > 0
+		case int:
+			return value > 0
+		default:
+			return false
 		}
+	default:
+		return true
 	}
 }
 
-func SetUpDB(ctx context.Context, db interface{}, createGraphQuery []string, n int) {
+// Loads the graph created by createGraphQuery, of n nodes, in the database
+func SetUpDB(ctx context.Context, db interface{}, createGraphQuery []string, n int) error {
 	switch db.(type) {
 	case neo4j.DriverWithContext:
-		setUpNeo4jDB(ctx, db.(neo4j.DriverWithContext), createGraphQuery, n)
+		return setUpNeo4jDB(ctx, db.(neo4j.DriverWithContext), createGraphQuery, n)
 	case *pgxpool.Pool:
-		setUpPostgresDB(ctx, db.(*pgxpool.Pool), createGraphQuery)
+		return setUpPostgresDB(ctx, db.(*pgxpool.Pool), createGraphQuery)
 	case *sql.DB:
-		SetUpDuckDB(ctx, db.(*sql.DB), createGraphQuery)
+		return SetUpDuckDB(ctx, db.(*sql.DB), createGraphQuery)
 	default:
-		panic(errors.New("ExecuteQuery : Database type unknown. This should not happen!"))
+		return errors.New("SetUpDB : Database type unknown. This should not happen!")
 	}
 }
 
-func setUpNeo4jDB(ctx context.Context, db neo4j.DriverWithContext, createGraphQuery []string, n int) {
-	CleanUpDB(ctx, db, n)
+func setUpNeo4jDB(ctx context.Context, db neo4j.DriverWithContext, createGraphQuery []string, n int) error {
+	if err := CleanUpDB(ctx, db, n); err != nil {
+		return err
+	}
 	session := db.NewSession(ctx, neo4j.SessionConfig{})
-	defer HandleClose(ctx, session)
+	defer session.Close(ctx)
 	for _, subQuery := range createGraphQuery {
 		_, err := neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (interface{}, error) {
 			_, err := tx.Run(ctx, subQuery, nil)
-			checkErr(err)
-			return 1, nil
+			return 1, err
 		})
-		checkErr(err)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func setUpPostgresDB(ctx context.Context, db *pgxpool.Pool, createGraphQuery []string) {
+func setUpPostgresDB(ctx context.Context, db *pgxpool.Pool, createGraphQuery []string) error {
 	for _, subQuery := range createGraphQuery {
-		_, err := db.Exec(ctx, subQuery)
-		checkErr(err)
+		if _, err := db.Exec(ctx, subQuery); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func SetUpDuckDB(ctx context.Context, db *sql.DB, createGraphQuery []string) {
+func SetUpDuckDB(ctx context.Context, db *sql.DB, createGraphQuery []string) error {
 	for _, subQuery := range createGraphQuery {
-		_, err := db.Exec(subQuery)
-		checkErr(err)
+		if _, err := db.Exec(subQuery); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func CleanUpDB(ctx context.Context, db interface{}, n int) {
+// Deletes the graph of n nodes from the database, or everything if n is -1
+func CleanUpDB(ctx context.Context, db interface{}, n int) error {
 	switch db.(type) {
 	case neo4j.DriverWithContext:
-		cleanUpNeo4j(ctx, db.(neo4j.DriverWithContext), n)
-	case *pgxpool.Pool: //SQL create graph queries already drop the required tables
-	case *sql.DB:
+		return cleanUpNeo4j(ctx, db.(neo4j.DriverWithContext), n)
+	case *pgxpool.Pool, *sql.DB: //SQL create graph queries already drop the required tables, so only a full clean up does anything
+		if n == -1 {
+			return dropSQLTables(ctx, db)
+		}
+		return nil
 	default:
-		panic(errors.New("CleanUpDB : Database type unknown. This should not happen!"))
+		return errors.New("CleanUpDB : Database type unknown. This should not happen!")
 	}
 }
 
-func cleanUpNeo4j(ctx context.Context, db neo4j.DriverWithContext, n int) {
+func dropSQLTables(ctx context.Context, db interface{}) error {
+	for _, dropQuery := range []string{"DROP TABLE IF EXISTS G;", "DROP TABLE IF EXISTS V;", "DROP TABLE IF EXISTS A;", "DROP TABLE IF EXISTS B;", "DROP SEQUENCE IF EXISTS serial;"} {
+		var err error
+		switch db.(type) {
+		case *pgxpool.Pool:
+			_, err = db.(*pgxpool.Pool).Exec(ctx, dropQuery)
+		case *sql.DB:
+			_, err = db.(*sql.DB).ExecContext(ctx, dropQuery)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cleanUpNeo4j(ctx context.Context, db neo4j.DriverWithContext, n int) error {
 	session := db.NewSession(ctx, neo4j.SessionConfig{})
-	defer HandleClose(ctx, session)
-	if n == -1 {
+	defer session.Close(ctx)
+	deleteQueries := []string{"MATCH (n) DETACH DELETE n"}
+	if n != -1 {
+		deleteQueries = make([]string, 0)
+		for i := 0; i < n; i++ {
+			deleteQueries = append(deleteQueries, fmt.Sprintf("MATCH (n {name:%d}) DETACH DELETE n", i))
+		}
+	}
+	for _, deleteQuery := range deleteQueries {
 		_, err := neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (interface{}, error) {
-			_, err := tx.Run(ctx, "MATCH (n) DETACH DELETE n", nil)
-			checkErr(err)
-			return 1, nil
+			_, err := tx.Run(ctx, deleteQuery, nil)
+			return 1, err
 		})
-		checkErr(err)
-	} else {
-		for i := 0; i < n; i++ {
-			deleteQuery := fmt.Sprintf("MATCH (n {name:%d}) DETACH DELETE n", i)
-			_, err := neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (interface{}, error) {
-				_, err := tx.Run(ctx, deleteQuery, nil)
-				checkErr(err)
-				return 1, nil
-			})
-			checkErr(err)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// Clears the query caches of neo4j, memgraph or postgres. For postgres, every connection of the pool is also replaced by a fresh one.
+// The duckDB database has to be reopened instead.
+func ClearCaches(ctx context.Context, db interface{}, memgraph bool) error {
+	switch db.(type) {
+	case neo4j.DriverWithContext:
+		session := db.(neo4j.DriverWithContext).NewSession(ctx, neo4j.SessionConfig{})
+		defer session.Close(ctx)
+		clearQuery := "CALL db.clearQueryCaches()"
+		if memgraph {
+			clearQuery = "FREE MEMORY"
+		}
+		result, err := session.Run(ctx, clearQuery, nil)
+		if err != nil {
+			return err
+		}
+		_, err = result.Consume(ctx)
+		return err
+	case *pgxpool.Pool:
+		db.(*pgxpool.Pool).Reset() // the connections acquired afterwards are new, without any session state
+		return nil
+	default:
+		return errors.New("ClearCaches : Database type unknown. This should not happen!")
+	}
 }
 
 type QueryResult struct {
 	QExecTime int
 	Found     bool
-}
-
-type ctxCloser interface {
-	Close(context.Context) error
-}
-
-func HandleClose(ctx context.Context, closer ctxCloser) {
-	checkErr(closer.Close(ctx))
-}
-
-func checkErr(err error) {
-	if err != nil {
-		panic(err)
-	}
+	Err       error // why the query failed, when QExecTime is -5
 }
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// How the source and target nodes of a graph or a query are chosen.
+// Components and distances ignore the direction of edges.
+type EndpointStrategy string
+
+const (
+	UniformEndpoints    EndpointStrategy = "uniform"    // any two nodes, possibly the same
+	DistinctEndpoints   EndpointStrategy = "distinct"   // two different nodes
+	SameComponent       EndpointStrategy = "component"  // two different nodes of the same connected component, when possible
+	FarthestEndpoints   EndpointStrategy = "farthest"   // two nodes at the maximum shortest-path distance
+	HighDegreeEndpoints EndpointStrategy = "highDegree" // the nodes with the most edges
+	LowDegreeEndpoints  EndpointStrategy = "lowDegree"  // the nodes with the fewest edges
+)
+
+var EndpointStrategies = []EndpointStrategy{UniformEndpoints, DistinctEndpoints, SameComponent, FarthestEndpoints, HighDegreeEndpoints, LowDegreeEndpoints}
+
+func ParseEndpointStrategy(name string) (EndpointStrategy, error) {
+	for _, s := range EndpointStrategies {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown endpoint strategy %q, expected one of %v", name, EndpointStrategies)
+}
+
+// Picks the given number of source/target pairs of nodes of g, returned as source1, target1, source2, target2...
+func (s EndpointStrategy) Pick(g *Graph, pairs int) []int {
+	nodes := make([]int, 0, 2*pairs)
+	switch s {
+	case HighDegreeEndpoints, LowDegreeEndpoints:
+		ranking := g.degreeRanking(s == HighDegreeEndpoints)
+		for i := 0; i < 2*pairs; i++ {
+			nodes = append(nodes, ranking[i%len(ranking)])
+		}
+	case FarthestEndpoints:
+		farthest := g.farthestPairs()
+		for i := 0; i < pairs; i++ {
+			pair := farthest[rng.Intn(len(farthest))]
+			nodes = append(nodes, pair[0], pair[1])
+		}
+	default:
+		for i := 0; i < pairs; i++ {
+			source, target := s.pickPair(g)
+			nodes = append(nodes, source, target)
+		}
+	}
+	return nodes
+}
+
+func (s EndpointStrategy) pickPair(g *Graph) (int, int) {
+	source := g.randomNode()
+	switch s {
+	case DistinctEndpoints:
+		return source, randomOtherNode(source, allNodes(g.Nodes))
+	case SameComponent:
+		dist := g.distancesFrom(g.neighbours(), source)
+		component := make([]int, 0)
+		for node, d := range dist {
+			if d != -1 {
+				component = append(component, node)
+			}
+		}
+		return source, randomOtherNode(source, component)
+	default:
+		return source, g.randomNode()
+	}
+}
+
+// Returns a random node of candidates other than node, or node if there is none
+func randomOtherNode(node int, candidates []int) int {
+	others := make([]int, 0, len(candidates))
+	for _, c := range candidates {
+		if c != node {
+			others = append(others, c)
+		}
+	}
+	if len(others) == 0 {
+		return node
+	}
+	return others[rng.Intn(len(others))]
+}
+
+func allNodes(n int) []int {
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	return nodes
+}
+
+// Returns the nodes sorted by degree, ties being broken at random
+func (g *Graph) degreeRanking(highestFirst bool) []int {
+	deg := g.degrees()
+	ranking := rng.Perm(g.Nodes)
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if highestFirst {
+			return deg[ranking[i]] > deg[ranking[j]]
+		}
+		return deg[ranking[i]] < deg[ranking[j]]
+	})
+	return ranking
+}
+
+// Returns every pair of nodes whose shortest-path distance is the largest one in the graph
+func (g *Graph) farthestPairs() [][2]int {
+	adj := g.neighbours()
+	maxDist := 0
+	pairs := [][2]int{}
+	for source := 0; source < g.Nodes; source++ {
+		for target, d := range g.distancesFrom(adj, source) {
+			if d > maxDist {
+				maxDist = d
+				pairs = pairs[:0]
+			}
+			if d == maxDist {
+				pairs = append(pairs, [2]int{source, target})
+			}
+		}
+	}
+	return pairs
+}
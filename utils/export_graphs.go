@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A format graphs can be exported to, so that large graphs are loaded with the native tools of a system
+type ExportFormat string
+
+const (
+	Neo4jAdminExport   ExportFormat = "neo4j-admin" // node and relationship CSVs with header files, for neo4j-admin database import
+	PostgresCopyExport ExportFormat = "postgres"    // one CSV per table, loaded with COPY
+	ParquetExport      ExportFormat = "parquet"     // one Parquet file per table, read by duckDB
+	GraphMLExport      ExportFormat = "graphml"     // which corpus import reads back
+)
+
+var ExportFormats = []ExportFormat{Neo4jAdminExport, PostgresCopyExport, ParquetExport, GraphMLExport}
+
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, f := range ExportFormats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of %v", name, ExportFormats)
+}
+
+// The system that loads the files of each format, whose view of the graph is exported
+var exportBackends = map[ExportFormat]Backend{
+	Neo4jAdminExport:   Neo4j,
+	PostgresCopyExport: Postgres,
+	ParquetExport:      DuckDB,
+	GraphMLExport:      Postgres,
+}
+
+// Writes g to dir in format, along with a script loading it, and returns the names of the files written.
+// Loaded files create the same graph as GraphScript : the same nodes, labels and properties for neo4j,
+// and the same tables for postgres and duckDB. Undirected graphs are exported as in paired runs, see PairedView.
+func ExportGraph(dir string, g *Graph, format ExportFormat) ([]string, error) {
+	b, found := exportBackends[format]
+	if !found {
+		_, err := ParseExportFormat(string(format))
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	g = PairedView(g, b)
+	switch format {
+	case Neo4jAdminExport:
+		return exportNeo4jAdmin(dir, g)
+	case GraphMLExport:
+		return exportGraphML(dir, g)
+	default:
+		return exportTables(dir, g, format)
+	}
+}
+
+// Writes a file of dir, through a function writing its content
+func writeExportFile(dir string, name string, write func(w *bufio.Writer) error) (string, error) {
+	name = filepath.Join(dir, name)
+	file, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	if err := write(w); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return name, file.Close()
+}
+
+// Writes rows as a CSV file of dir
+func writeCSV(dir string, name string, rows [][]string) (string, error) {
+	return writeExportFile(dir, name, func(w *bufio.Writer) error {
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	})
+}
+
+// Nodes keep their name and val properties and their Start/End labels, and relationships their type and value or val property, as in CypherScript.
+// The ids of the import are the names of the nodes.
+func exportNeo4jAdmin(dir string, g *Graph) ([]string, error) {
+	nodeHeader := []string{":ID", "name:int"}
+	if g.Values != nil {
+		nodeHeader = append(nodeHeader, "val:int")
+	}
+	nodeHeader = append(nodeHeader, ":LABEL")
+	nodes := make([][]string, 0, g.Nodes)
+	for i := 0; i < g.Nodes; i++ {
+		row := []string{strconv.Itoa(i), strconv.Itoa(i)}
+		if g.Values != nil {
+			row = append(row, strconv.Itoa(g.Values[i]))
+		}
+		labels := make([]string, 0)
+		if i == g.Start {
+			labels = append(labels, "Start")
+		}
+		if i == g.End {
+			labels = append(labels, "End")
+		}
+		nodes = append(nodes, append(row, strings.Join(labels, ";")))
+	}
+
+	relationshipHeader := []string{":START_ID", ":END_ID", ":TYPE"}
+	property := ""
+	switch g.Family {
+	case DoubleLineGraph:
+		property = "value"
+	case EdgeValueGraph:
+		property = "val"
+	}
+	if property != "" {
+		relationshipHeader = append(relationshipHeader, property+":int")
+	}
+	relationships := make([][]string, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		row := []string{strconv.Itoa(e.Src), strconv.Itoa(e.Trg), e.Label}
+		if property != "" {
+			row = append(row, strconv.Itoa(e.Value))
+		}
+		relationships = append(relationships, row)
+	}
+
+	files := make([]string, 0)
+	for _, f := range []struct {
+		name string
+		rows [][]string
+	}{
+		{"nodes_header.csv", [][]string{nodeHeader}},
+		{"nodes.csv", nodes},
+		{"relationships_header.csv", [][]string{relationshipHeader}},
+		{"relationships.csv", relationships},
+	} {
+		name, err := writeCSV(dir, f.name, f.rows)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	name, err := writeExportFile(dir, "import.sh", func(w *bufio.Writer) error {
+		_, err := fmt.Fprintf(w, "#!/bin/sh\n# Replaces the database (neo4j by default) with the graph. Run from this directory while the database is stopped.\n"+
+			"neo4j-admin database import full --nodes=nodes_header.csv,nodes.csv --relationships=relationships_header.csv,relationships.csv --overwrite-destination \"${1:-neo4j}\"\n")
+		return err
+	})
+	if err != nil {
+		return files, err
+	}
+	return append(files, name), os.Chmod(name, 0755)
+}
+
+// The tables of g are those created by the script of its format's system, read back from an in-memory duckDB database.
+// The load script creates them as the script does, then copies the rows of the files in.
+func exportTables(dir string, g *Graph, format ExportFormat) ([]string, error) {
+	b := exportBackends[format]
+	script := GraphScript(b, g)
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := SetUpDuckDB(context.Background(), db, GraphScript(DuckDB, g)); err != nil {
+		return nil, err
+	}
+
+	load := []string{"-- Creates the graph, run from this directory"}
+	for _, statement := range scriptStatements(script) {
+		if !strings.HasPrefix(strings.ToUpper(statement), "INSERT") {
+			load = append(load, statement+";")
+		}
+	}
+	files := make([]string, 0)
+	for _, table := range []string{"G", "A", "B", "V"} {
+		var count int
+		if err := db.QueryRow("SELECT count(*) FROM information_schema.tables WHERE table_name = ?", table).Scan(&count); err != nil {
+			return files, err
+		}
+		if count == 0 {
+			continue
+		}
+		var name string
+		if format == ParquetExport {
+			name = table + ".parquet"
+			_, err = db.Exec(fmt.Sprintf("COPY %v TO '%v' (FORMAT parquet);", table, filepath.Join(dir, name)))
+			load = append(load, fmt.Sprintf("INSERT INTO %v SELECT * FROM read_parquet('%v');", table, name))
+		} else {
+			// The labeled tables of postgres number their edges with a serial column each, which COPY fills as the inserts of the script do
+			columns, source := "", table
+			if table == "A" || table == "B" {
+				columns, source = " (s, t)", fmt.Sprintf("(SELECT s, t FROM %v ORDER BY id)", table)
+			}
+			name = table + ".csv"
+			_, err = db.Exec(fmt.Sprintf("COPY %v TO '%v' (FORMAT csv, HEADER);", source, filepath.Join(dir, name)))
+			load = append(load, fmt.Sprintf("\\copy %v%v FROM '%v' WITH (FORMAT csv, HEADER true)", table, columns, name))
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	name, err := writeExportFile(dir, "load.sql", func(w *bufio.Writer) error {
+		_, err := fmt.Fprintln(w, strings.Join(load, "\n"))
+		return err
+	})
+	if err != nil {
+		return files, err
+	}
+	return append(files, name), nil
+}
+
+// Splits the queries of a script into statements, as scripts of double line graphs are a single query
+func scriptStatements(script []string) []string {
+	statements := make([]string, 0)
+	for _, query := range script {
+		for _, statement := range strings.Split(query, ";") {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				statements = append(statements, statement)
+			}
+		}
+	}
+	return statements
+}
+
+// Nodes have a value if the graph has node values, and the start and end nodes a start/end attribute.
+// Edges have a label, and a weight in double line and edge value graphs. Undirected graphs are written with each edge once.
+func exportGraphML(dir string, g *Graph) ([]string, error) {
+	type node struct {
+		ID   string        `xml:"id,attr"`
+		Data []graphMLData `xml:"data"`
+	}
+	type edge struct {
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+	type document struct {
+		XMLName xml.Name     `xml:"graphml"`
+		XMLNS   string       `xml:"xmlns,attr"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	weighted := g.Family == DoubleLineGraph || g.Family == EdgeValueGraph
+	doc := document{XMLNS: "http://graphml.graphdrawing.org/xmlns"}
+	doc.Keys = []graphMLKey{{"start", "node", "start", "boolean"}, {"end", "node", "end", "boolean"}, {"label", "edge", "label", "string"}}
+	if g.Values != nil {
+		doc.Keys = append(doc.Keys, graphMLKey{"value", "node", "value", "int"})
+	}
+	if weighted {
+		doc.Keys = append(doc.Keys, graphMLKey{"weight", "edge", "weight", "int"})
+	}
+	doc.Graph.EdgeDefault = "directed"
+	if g.Undirected {
+		doc.Graph.EdgeDefault = "undirected"
+	}
+	for i := 0; i < g.Nodes; i++ {
+		n := node{ID: strconv.Itoa(i)}
+		if g.Values != nil {
+			n.Data = append(n.Data, graphMLData{"value", strconv.Itoa(g.Values[i])})
+		}
+		if i == g.Start {
+			n.Data = append(n.Data, graphMLData{"start", "true"})
+		}
+		if i == g.End {
+			n.Data = append(n.Data, graphMLData{"end", "true"})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+	}
+	for _, e := range g.Edges {
+		if g.Undirected && e.Src > e.Trg {
+			continue
+		}
+		ed := edge{Source: strconv.Itoa(e.Src), Target: strconv.Itoa(e.Trg), Data: []graphMLData{{"label", e.Label}}}
+		if weighted {
+			ed.Data = append(ed.Data, graphMLData{"weight", strconv.Itoa(e.Value)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, ed)
+	}
+
+	name, err := writeExportFile(dir, "graph.graphml", func(w *bufio.Writer) error {
+		if _, err := w.WriteString(xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		_, err := w.WriteString("\n")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
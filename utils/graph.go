@@ -0,0 +1,69 @@
+package utils
+
+// A generated graph, independent of the system it is loaded in.
+// Nodes are numbered from 0 to Nodes-1.
+type Graph struct {
+	Family GraphFamily
+	Nodes  int
+	Edges  []Edge
+	Values []int // the value of each node, nil if nodes have no value
+	Start  int   // the node labeled Start, -1 if there is none
+	End    int   // the node labeled End, -1 if there is none
+	// Whether every edge is stored in both directions, as the SQL queries expect of an undirected graph
+	Undirected bool
+}
+
+type Edge struct {
+	Src   int
+	Trg   int
+	Label string // Edge, or a/b in labeled graphs
+	Value int    // only meaningful in double line and edge value graphs
+}
+
+// Returns the neighbours of every node, ignoring the direction of edges
+// like the undirected patterns of the Cypher queries
+func (g *Graph) neighbours() [][]int {
+	adj := make([][]int, g.Nodes)
+	for _, e := range g.Edges {
+		adj[e.Src] = append(adj[e.Src], e.Trg)
+		if e.Src != e.Trg {
+			adj[e.Trg] = append(adj[e.Trg], e.Src)
+		}
+	}
+	return adj
+}
+
+// Returns the number of edges touching each node, self loops counting twice
+func (g *Graph) degrees() []int {
+	deg := make([]int, g.Nodes)
+	for _, e := range g.Edges {
+		deg[e.Src]++
+		deg[e.Trg]++
+	}
+	return deg
+}
+
+// Returns the length of a shortest undirected path from source to every node, -1 for unreachable nodes
+func (g *Graph) distancesFrom(adj [][]int, source int) []int {
+	dist := make([]int, g.Nodes)
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[source] = 0
+	queue := []int{source}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[node] {
+			if dist[next] == -1 {
+				dist[next] = dist[node] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return dist
+}
+
+func (g *Graph) randomNode() int {
+	return rng.Intn(g.Nodes)
+}
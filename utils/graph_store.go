@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Graphs are stored once in a directory, as gzipped edge lists named after the hash of their content :
+//
+//	family random
+//	nodes 5
+//	undirected
+//	start 0
+//	end 4
+//	values 3 -1 0 2 7
+//	0 1 Edge 0
+//
+// The undirected, start, end and values lines are only written when relevant. Each edge is written as its source, target, label and value,
+// in the order of the graph, so that loading a stored graph creates the same tables as the original one.
+
+const graphFileSuffix = ".edges.gz"
+
+// Writes the edge list of g
+func EncodeGraph(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "family %v\nnodes %v\n", g.Family, g.Nodes)
+	if g.Undirected {
+		fmt.Fprintln(b, "undirected")
+	}
+	if g.Start != -1 {
+		fmt.Fprintf(b, "start %v\n", g.Start)
+	}
+	if g.End != -1 {
+		fmt.Fprintf(b, "end %v\n", g.End)
+	}
+	if g.Values != nil {
+		values := make([]string, len(g.Values))
+		for i, v := range g.Values {
+			values[i] = strconv.Itoa(v)
+		}
+		fmt.Fprintf(b, "values %v\n", strings.Join(values, " "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "%v %v %v %v\n", e.Src, e.Trg, e.Label, e.Value)
+	}
+	return b.Flush()
+}
+
+// Reads an edge list written by EncodeGraph
+func DecodeGraph(r io.Reader) (*Graph, error) {
+	g := newGraph(RandomGraph, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024) // the values of large graphs are written on one line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "family":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("malformed edge list line : %v", scanner.Text())
+			}
+			g.Family = GraphFamily(fields[1])
+		case "nodes":
+			g.Nodes, err = parseField(fields, 1)
+		case "undirected":
+			g.Undirected = true
+		case "start":
+			g.Start, err = parseField(fields, 1)
+		case "end":
+			g.End, err = parseField(fields, 1)
+		case "values":
+			g.Values = make([]int, len(fields)-1)
+			for i := range g.Values {
+				if g.Values[i], err = parseField(fields, i+1); err != nil {
+					break
+				}
+			}
+		default:
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("malformed edge list line : %v", scanner.Text())
+			}
+			e := Edge{Label: fields[2]}
+			if e.Src, err = parseField(fields, 0); err == nil {
+				if e.Trg, err = parseField(fields, 1); err == nil {
+					e.Value, err = parseField(fields, 3)
+				}
+			}
+			g.Edges = append(g.Edges, e)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return g, scanner.Err()
+}
+
+func parseField(fields []string, i int) (int, error) {
+	if i >= len(fields) {
+		return 0, errors.New("malformed edge list line : " + strings.Join(fields, " "))
+	}
+	return strconv.Atoi(fields[i])
+}
+
+// Returns the content hash of g, which names it in graph directories
+func GraphHash(g *Graph) string {
+	h := sha256.New()
+	EncodeGraph(h, g)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Stores g in dir, unless it is already there, and returns its hash
+func SaveGraph(dir string, g *Graph) (string, error) {
+	hash := GraphHash(g)
+	name := filepath.Join(dir, hash+graphFileSuffix)
+	if _, err := os.Stat(name); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// Written to a temporary file first, so that an interrupted run never leaves a truncated graph under its hash
+	tmp, err := os.CreateTemp(dir, hash+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	err = EncodeGraph(zw, g)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return hash, os.Rename(tmp.Name(), name)
+}
+
+// Returns the graph stored in dir under hash
+func LoadGraph(dir string, hash string) (*Graph, error) {
+	file, err := os.Open(filepath.Join(dir, hash+graphFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return DecodeGraph(zr)
+}
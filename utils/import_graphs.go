@@ -0,0 +1,451 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A file format graphs can be imported from
+type GraphFormat string
+
+const (
+	EdgeListFormat GraphFormat = "edgelist" // one edge per line : source target, then optionally a label and/or an integer weight
+	DIMACSColor    GraphFormat = "col"      // DIMACS graph coloring : p edge N M, then e u v, undirected
+	DIMACSPaths    GraphFormat = "gr"       // DIMACS shortest paths : p sp N M, then a u v w, directed and weighted
+	GraphMLFormat  GraphFormat = "graphml"
+	NodeLinkFormat GraphFormat = "json" // the node-link format of networkx and d3 : {"directed": ..., "nodes": [...], "links": [...]}
+)
+
+var GraphFormats = []GraphFormat{EdgeListFormat, DIMACSColor, DIMACSPaths, GraphMLFormat, NodeLinkFormat}
+
+func ParseGraphFormat(name string) (GraphFormat, error) {
+	for _, f := range GraphFormats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown graph format %q, expected one of %v", name, GraphFormats)
+}
+
+// Returns the format of a graph file, from its extension. Files of unknown extensions are read as edge lists.
+func GraphFormatOf(name string) GraphFormat {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".col":
+		return DIMACSColor
+	case ".gr":
+		return DIMACSPaths
+	case ".graphml", ".xml":
+		return GraphMLFormat
+	case ".json":
+		return NodeLinkFormat
+	default:
+		return EdgeListFormat
+	}
+}
+
+// How a graph file is turned into a graph
+type ImportOptions struct {
+	Format     GraphFormat      // guessed from the name of the file if empty
+	Family     GraphFamily      // inferred from the graph if empty, see ImportGraph
+	Undirected bool             // for edge lists, which cannot tell, and directed files to be read as undirected
+	Endpoints  EndpointStrategy // picks the start and end nodes of random and labeled graphs that name none
+}
+
+// Reads the graph of a file, see ImportGraph
+func ImportGraphFile(name string, options ImportOptions) (*Graph, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if options.Format == "" {
+		options.Format = GraphFormatOf(name)
+	}
+	g, err := ImportGraph(file, options)
+	if err != nil {
+		return nil, fmt.Errorf("%v : %w", name, err)
+	}
+	return g, nil
+}
+
+// Reads a graph in one of the GraphFormats. Nodes are numbered in the order they are declared or first used, and DIMACS nodes from 0.
+// Unless a family is given, graphs whose edges are all labeled a or b are labeled graphs, graphs with edge weights are edge value graphs,
+// graphs with node values are node value graphs, and other graphs random graphs : other labels are dropped.
+// Undirected graphs get both directions of every edge, as generated undirected graphs.
+// Edges are only kept once, except in double line and edge value graphs where parallel edges may differ by their value.
+// Nodes can be marked as the start and end nodes by a start/end attribute, or a Start/End label.
+func ImportGraph(r io.Reader, options ImportOptions) (*Graph, error) {
+	b := &graphBuilder{ids: make(map[string]int), values: make(map[int]int), undirected: options.Undirected, start: -1, end: -1}
+	var err error
+	switch options.Format {
+	case EdgeListFormat, "":
+		err = b.readEdgeList(r)
+	case DIMACSColor, DIMACSPaths:
+		err = b.readDIMACS(r)
+	case GraphMLFormat:
+		err = b.readGraphML(r)
+	case NodeLinkFormat:
+		err = b.readNodeLink(r)
+	default:
+		_, err = ParseGraphFormat(string(options.Format))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.build(options.Family, options.Endpoints)
+}
+
+// Collects the nodes and edges of a graph file
+type graphBuilder struct {
+	ids        map[string]int
+	nodes      int
+	edges      []Edge
+	weighted   bool
+	values     map[int]int
+	undirected bool
+	start, end int
+}
+
+func (b *graphBuilder) node(id string) int {
+	if i, found := b.ids[id]; found {
+		return i
+	}
+	b.ids[id] = b.nodes
+	b.nodes++
+	return b.nodes - 1
+}
+
+func (b *graphBuilder) edge(src string, trg string, label string, weight string) error {
+	e := Edge{Src: b.node(src), Trg: b.node(trg), Label: label}
+	if weight != "" {
+		value, err := strconv.Atoi(weight)
+		if err != nil {
+			return fmt.Errorf("edge weights must be integers, got %v", weight)
+		}
+		e.Value = value
+		b.weighted = true
+	}
+	b.edges = append(b.edges, e)
+	return nil
+}
+
+// Records the attributes of node id that the graph has a use for
+func (b *graphBuilder) nodeAttributes(id string, attributes map[string]string) error {
+	node := b.node(id)
+	for name, value := range attributes {
+		switch strings.ToLower(name) {
+		case "value", "val":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("node values must be integers, got %v", value)
+			}
+			b.values[node] = v
+		case "start", "end":
+			if set, _ := strconv.ParseBool(value); set {
+				b.mark(strings.ToLower(name), node)
+			}
+		case "label", "labels":
+			for _, label := range strings.FieldsFunc(value, func(r rune) bool { return r == ':' || r == ',' || r == ' ' }) {
+				b.mark(strings.ToLower(label), node)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) mark(label string, node int) {
+	switch label {
+	case "start":
+		b.start = node
+	case "end":
+		b.end = node
+	}
+}
+
+// Returns the label and weight among the attributes of an edge
+func edgeAttributes(attributes map[string]string) (string, string) {
+	label, weight := "", ""
+	for name, value := range attributes {
+		switch strings.ToLower(name) {
+		case "label", "type":
+			label = value
+		case "weight", "value", "val":
+			weight = value
+		}
+	}
+	return label, weight
+}
+
+func (b *graphBuilder) build(family GraphFamily, endpoints EndpointStrategy) (*Graph, error) {
+	labeled := len(b.edges) > 0
+	for _, e := range b.edges {
+		labeled = labeled && (e.Label == "a" || e.Label == "b")
+	}
+	if family == "" {
+		switch {
+		case labeled:
+			family = LabeledGraph
+		case b.weighted:
+			family = EdgeValueGraph
+		case len(b.values) > 0:
+			family = NodeValueGraph
+		default:
+			family = RandomGraph
+		}
+	}
+	if _, ok := family.Generator(Neo4j); !ok {
+		return nil, fmt.Errorf("unknown graph family %v", family)
+	}
+	if family == LabeledGraph && !labeled {
+		return nil, fmt.Errorf("the edges of labeled graphs must be labeled a or b")
+	}
+
+	g := newGraph(family, b.nodes)
+	g.Undirected = b.undirected
+	if family == NodeValueGraph {
+		g.Values = make([]int, b.nodes)
+		for node, value := range b.values {
+			g.Values[node] = value
+		}
+	}
+	parallel := family == DoubleLineGraph || family == EdgeValueGraph
+	seen := make(map[Edge]bool)
+	add := func(e Edge) {
+		if family != LabeledGraph {
+			e.Label = "Edge"
+		}
+		if !parallel {
+			e.Value = 0
+		}
+		if parallel || !seen[e] {
+			seen[e] = true
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	for _, e := range b.edges {
+		add(e)
+		if b.undirected && e.Src != e.Trg {
+			add(Edge{Src: e.Trg, Trg: e.Src, Label: e.Label, Value: e.Value})
+		}
+	}
+
+	g.Start, g.End = b.start, b.end
+	if g.Start == -1 && g.End == -1 && g.Nodes > 0 {
+		switch family {
+		case RandomGraph, LabeledGraph:
+			g.pickStartEnd(endpoints)
+		case DoubleLineGraph:
+			g.Start, g.End = 0, g.Nodes-1
+		}
+	}
+	return g, nil
+}
+
+func (b *graphBuilder) readEdgeList(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+		fields := strings.Fields(line)
+		label, weight := "", ""
+		switch len(fields) {
+		case 2:
+		case 3:
+			if _, err := strconv.Atoi(fields[2]); err == nil {
+				weight = fields[2]
+			} else {
+				label = fields[2]
+			}
+		case 4:
+			label, weight = fields[2], fields[3]
+			if _, err := strconv.Atoi(label); err == nil {
+				label, weight = weight, label
+			}
+		default:
+			return fmt.Errorf("expected source, target and optionally a label and a weight, got %q", line)
+		}
+		if err := b.edge(fields[0], fields[1], label, weight); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (b *graphBuilder) readDIMACS(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		id := func(s string) (string, error) { // DIMACS nodes are numbered from 1
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 1 {
+				return "", fmt.Errorf("invalid DIMACS node %v", s)
+			}
+			return strconv.Itoa(i - 1), nil
+		}
+		switch {
+		case fields[0] == "c":
+		case fields[0] == "p" && len(fields) >= 3:
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return fmt.Errorf("invalid DIMACS problem line %q", scanner.Text())
+			}
+			b.undirected = b.undirected || fields[1] == "edge" || fields[1] == "col"
+			for i := 0; i < n; i++ {
+				b.node(strconv.Itoa(i))
+			}
+		case (fields[0] == "e" || fields[0] == "a") && len(fields) >= 3:
+			src, err := id(fields[1])
+			if err != nil {
+				return err
+			}
+			trg, err := id(fields[2])
+			if err != nil {
+				return err
+			}
+			weight := ""
+			if len(fields) >= 4 {
+				weight = fields[3]
+			}
+			if err := b.edge(src, trg, "", weight); err != nil {
+				return err
+			}
+		case fields[0] == "n" && len(fields) >= 3: // node weights of coloring instances
+			node, err := id(fields[1])
+			if err != nil {
+				return err
+			}
+			if err := b.nodeAttributes(node, map[string]string{"value": fields[2]}); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unrecognized DIMACS line %q", scanner.Text())
+		}
+	}
+	return scanner.Err()
+}
+
+type graphMLDocument struct {
+	Keys  []graphMLKey `xml:"key"`
+	Graph struct {
+		EdgeDefault string `xml:"edgedefault,attr"`
+		Nodes       []struct {
+			ID   string        `xml:"id,attr"`
+			Data []graphMLData `xml:"data"`
+		} `xml:"node"`
+		Edges []struct {
+			Source string        `xml:"source,attr"`
+			Target string        `xml:"target,attr"`
+			Data   []graphMLData `xml:"data"`
+		} `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	Name     string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+func (b *graphBuilder) readGraphML(r io.Reader) error {
+	var doc graphMLDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	for _, key := range doc.Keys {
+		names[key.ID] = key.Name
+	}
+	attributes := func(data []graphMLData) map[string]string {
+		attrs := make(map[string]string)
+		for _, d := range data {
+			name := names[d.Key]
+			if name == "" {
+				name = d.Key
+			}
+			attrs[name] = strings.TrimSpace(d.Value)
+		}
+		return attrs
+	}
+	b.undirected = b.undirected || doc.Graph.EdgeDefault == "undirected"
+	for _, node := range doc.Graph.Nodes {
+		if err := b.nodeAttributes(node.ID, attributes(node.Data)); err != nil {
+			return err
+		}
+	}
+	for _, edge := range doc.Graph.Edges {
+		label, weight := edgeAttributes(attributes(edge.Data))
+		if err := b.edge(edge.Source, edge.Target, label, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) readNodeLink(r io.Reader) error {
+	var doc struct {
+		Directed *bool                    `json:"directed"`
+		Nodes    []map[string]interface{} `json:"nodes"`
+		Links    []map[string]interface{} `json:"links"`
+		Edges    []map[string]interface{} `json:"edges"` // the name of links in recent versions of networkx
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+	attributes := func(object map[string]interface{}) map[string]string {
+		attrs := make(map[string]string)
+		for name, value := range object {
+			if list, isList := value.([]interface{}); isList { // labels of neo4j exports
+				parts := make([]string, len(list))
+				for i, v := range list {
+					parts[i] = fmt.Sprint(v)
+				}
+				attrs[name] = strings.Join(parts, ",")
+			} else {
+				attrs[name] = fmt.Sprint(value)
+			}
+		}
+		return attrs
+	}
+	b.undirected = b.undirected || doc.Directed != nil && !*doc.Directed
+	for _, node := range doc.Nodes {
+		id, found := node["id"]
+		if !found {
+			return fmt.Errorf("node without id : %v", node)
+		}
+		if err := b.nodeAttributes(fmt.Sprint(id), attributes(node)); err != nil {
+			return err
+		}
+	}
+	for _, link := range append(doc.Links, doc.Edges...) {
+		src, hasSrc := link["source"]
+		trg, hasTrg := link["target"]
+		if !hasSrc || !hasTrg {
+			return fmt.Errorf("link without source or target : %v", link)
+		}
+		label, weight := edgeAttributes(attributes(link))
+		if err := b.edge(fmt.Sprint(src), fmt.Sprint(trg), label, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The start and end nodes of graphs that name none are drawn at random
+const anyNode = -2
+
+func TestImportGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ImportOptions
+		input   string
+		want    Graph
+	}{
+		{"edge list", ImportOptions{Format: EdgeListFormat}, "# a comment\n1 2\n\n2 3\n% another one\n2 3\n",
+			Graph{Family: RandomGraph, Nodes: 3, Edges: []Edge{{0, 1, "Edge", 0}, {1, 2, "Edge", 0}}, Start: anyNode, End: anyNode}},
+		{"labeled undirected edge list", ImportOptions{Format: EdgeListFormat, Undirected: true}, "x y a\ny z b\n",
+			Graph{Family: LabeledGraph, Nodes: 3, Edges: []Edge{{0, 1, "a", 0}, {1, 0, "a", 0}, {1, 2, "b", 0}, {2, 1, "b", 0}}, Start: anyNode, End: anyNode, Undirected: true}},
+		{"weighted edge list", ImportOptions{Format: EdgeListFormat}, "0 1 5\n0 1 -3\n1 2 road 4\n2 0 7 road\n",
+			Graph{Family: EdgeValueGraph, Nodes: 3, Edges: []Edge{{0, 1, "Edge", 5}, {0, 1, "Edge", -3}, {1, 2, "Edge", 4}, {2, 0, "Edge", 7}}, Start: -1, End: -1}},
+		{"edge list as double line graph", ImportOptions{Format: EdgeListFormat, Family: DoubleLineGraph}, "0 1 1\n0 1 -1\n1 2 0\n",
+			Graph{Family: DoubleLineGraph, Nodes: 3, Edges: []Edge{{0, 1, "Edge", 1}, {0, 1, "Edge", -1}, {1, 2, "Edge", 0}}, Start: 0, End: 2}},
+		{"DIMACS coloring", ImportOptions{Format: DIMACSColor}, "c nodes are numbered from 1\np edge 4 2\ne 1 2\ne 4 3\n",
+			Graph{Family: RandomGraph, Nodes: 4, Edges: []Edge{{0, 1, "Edge", 0}, {1, 0, "Edge", 0}, {3, 2, "Edge", 0}, {2, 3, "Edge", 0}}, Start: anyNode, End: anyNode, Undirected: true}},
+		{"DIMACS node weights", ImportOptions{Format: DIMACSColor}, "p col 3 1\nn 1 5\nn 3 -2\ne 1 3\n",
+			Graph{Family: NodeValueGraph, Nodes: 3, Edges: []Edge{{0, 2, "Edge", 0}, {2, 0, "Edge", 0}}, Values: []int{5, 0, -2}, Start: -1, End: -1, Undirected: true}},
+		{"DIMACS shortest paths", ImportOptions{Format: DIMACSPaths}, "c directed\np sp 3 2\na 1 2 7\na 2 3 -1\n",
+			Graph{Family: EdgeValueGraph, Nodes: 3, Edges: []Edge{{0, 1, "Edge", 7}, {1, 2, "Edge", -1}}, Start: -1, End: -1}},
+		{"GraphML", ImportOptions{Format: GraphMLFormat}, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="start" attr.type="boolean"/>
+  <key id="d1" for="node" attr.name="labels" attr.type="string"/>
+  <key id="d2" for="edge" attr.name="label" attr.type="string"/>
+  <graph edgedefault="directed">
+    <node id="n0"><data key="d0">true</data></node>
+    <node id="n1"/>
+    <node id="n2"><data key="d1">:Node:End</data></node>
+    <edge source="n0" target="n1"><data key="d2">a</data></edge>
+    <edge source="n1" target="n2"><data key="d2">b</data></edge>
+    <edge source="n1" target="n2"><data key="d2">b</data></edge>
+  </graph>
+</graphml>`,
+			Graph{Family: LabeledGraph, Nodes: 3, Edges: []Edge{{0, 1, "a", 0}, {1, 2, "b", 0}}, Start: 0, End: 2}},
+		{"undirected GraphML", ImportOptions{Format: GraphMLFormat}, `<graphml><graph edgedefault="undirected">
+    <node id="a"/><node id="b"/><edge source="b" target="a"/><edge source="a" target="a"/>
+  </graph></graphml>`,
+			Graph{Family: RandomGraph, Nodes: 2, Edges: []Edge{{1, 0, "Edge", 0}, {0, 1, "Edge", 0}, {0, 0, "Edge", 0}}, Start: anyNode, End: anyNode, Undirected: true}},
+		{"node-link", ImportOptions{Format: NodeLinkFormat}, `{"directed": false, "nodes": [
+    {"id": "x", "value": 3, "labels": ["Start"]}, {"id": "y", "value": -1}, {"id": "z", "value": 0, "labels": ["End"]}],
+  "links": [{"source": "x", "target": "y"}, {"source": "y", "target": "z"}]}`,
+			Graph{Family: NodeValueGraph, Nodes: 3, Edges: []Edge{{0, 1, "Edge", 0}, {1, 0, "Edge", 0}, {1, 2, "Edge", 0}, {2, 1, "Edge", 0}}, Values: []int{3, -1, 0}, Start: 0, End: 2, Undirected: true}},
+		{"networkx node-link", ImportOptions{Format: NodeLinkFormat}, `{"directed": true, "nodes": [{"id": 1}, {"id": 0}],
+  "edges": [{"source": 0, "target": 1, "weight": 2}, {"source": 1, "target": 0, "weight": -2}]}`,
+			Graph{Family: EdgeValueGraph, Nodes: 2, Edges: []Edge{{1, 0, "Edge", 2}, {0, 1, "Edge", -2}}, Start: -1, End: -1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := ImportGraph(strings.NewReader(test.input), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := test.want
+			if want.Start == anyNode {
+				if g.Start < 0 || g.Start >= g.Nodes || g.End < 0 || g.End >= g.Nodes {
+					t.Errorf("got start %v and end %v, want nodes of the graph", g.Start, g.End)
+				}
+				want.Start, want.End = g.Start, g.End
+			}
+			if !reflect.DeepEqual(*g, want) {
+				t.Errorf("got %+v, want %+v", *g, want)
+			}
+		})
+	}
+}
+
+func TestImportInvalidGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ImportOptions
+		input   string
+	}{
+		{"edge list of one column", ImportOptions{Format: EdgeListFormat}, "1\n"},
+		{"edge list weight", ImportOptions{Format: EdgeListFormat}, "1 2 a 1.5\n"},
+		{"unlabeled labeled graph", ImportOptions{Format: EdgeListFormat, Family: LabeledGraph}, "1 2 c\n"},
+		{"DIMACS node 0", ImportOptions{Format: DIMACSColor}, "p edge 2 1\ne 0 1\n"},
+		{"DIMACS line", ImportOptions{Format: DIMACSColor}, "p edge 2 1\nx 1 2\n"},
+		{"GraphML", ImportOptions{Format: GraphMLFormat}, "<graphml><graph>"},
+		{"node-link without id", ImportOptions{Format: NodeLinkFormat}, `{"nodes": [{"value": 1}], "links": []}`},
+		{"unknown format", ImportOptions{Format: "dot"}, "digraph {}"},
+	}
+	for _, test := range tests {
+		if _, err := ImportGraph(strings.NewReader(test.input), test.options); err == nil {
+			t.Errorf("%v was accepted", test.name)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+// Returns a smaller instance on which failing still holds, found by delta debugging :
+// ever smaller sets of edges, then of nodes, are removed while failing holds, until no single edge or node can be removed.
+// The start and end nodes and the nodes of the query are kept. The edges of undirected graphs are removed along with their reverse edge.
+func Minimize(g *Graph, nodes QueryParams, failing func(g *Graph, nodes QueryParams) bool) (*Graph, QueryParams) {
+	for {
+		size := g.Nodes + len(g.Edges)
+
+		edges := make([]int, 0, len(g.Edges))
+		for i, e := range g.Edges {
+			if !g.Undirected || e.Src <= e.Trg {
+				edges = append(edges, i)
+			}
+		}
+		kept := ddmin(edges, func(kept []int) bool {
+			return failing(g.keepingEdges(kept), nodes)
+		})
+		g = g.keepingEdges(kept)
+
+		removable := make([]int, 0, g.Nodes)
+		for v := 0; v < g.Nodes; v++ {
+			if !g.pinned(v, nodes) {
+				removable = append(removable, v)
+			}
+		}
+		kept = ddmin(removable, func(kept []int) bool {
+			h, hNodes := g.withoutNodes(removable, kept, nodes)
+			return failing(h, hNodes)
+		})
+		g, nodes = g.withoutNodes(removable, kept, nodes)
+
+		if g.Nodes+len(g.Edges) == size {
+			return g, nodes
+		}
+	}
+}
+
+// Returns a subset of units on which failing holds, such that removing any single unit of it makes failing false.
+// failing must hold on units.
+func ddmin(units []int, failing func(kept []int) bool) []int {
+	chunks := 2
+	for len(units) > 0 {
+		if len(units) == 1 {
+			if failing([]int{}) {
+				return []int{}
+			}
+			return units
+		}
+		size := (len(units) + chunks - 1) / chunks
+		reduced := false
+		for start := 0; start < len(units) && !reduced; start += size {
+			end := start + size
+			if end > len(units) {
+				end = len(units)
+			}
+			complement := append(append([]int{}, units[:start]...), units[end:]...)
+			if failing(complement) {
+				units, reduced = complement, true
+				if chunks > 2 {
+					chunks--
+				}
+			}
+		}
+		if !reduced {
+			if chunks >= len(units) {
+				return units
+			}
+			chunks *= 2
+			if chunks > len(units) {
+				chunks = len(units)
+			}
+		}
+	}
+	return units
+}
+
+// Whether node v is the start or end node of g, or one of the nodes of the query
+func (g *Graph) pinned(v int, nodes QueryParams) bool {
+	if v == g.Start || v == g.End {
+		return true
+	}
+	for _, node := range nodes {
+		if node == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of g with only the edges of the given indexes, along with their reverse edges if g is undirected
+func (g *Graph) keepingEdges(kept []int) *Graph {
+	keep := make(map[[2]int]bool)
+	for _, i := range kept {
+		e := g.Edges[i]
+		keep[[2]int{e.Src, e.Trg}] = true
+		if g.Undirected {
+			keep[[2]int{e.Trg, e.Src}] = true
+		}
+	}
+	h := *g
+	h.Edges = make([]Edge, 0, len(kept))
+	if g.Undirected {
+		for _, e := range g.Edges {
+			if keep[[2]int{e.Src, e.Trg}] {
+				h.Edges = append(h.Edges, e)
+			}
+		}
+		return &h
+	}
+	for _, i := range kept {
+		h.Edges = append(h.Edges, g.Edges[i])
+	}
+	return &h
+}
+
+// Returns a copy of g without the nodes of removable that are not kept, nor their edges, the other nodes being renumbered,
+// along with the renumbered nodes of the query
+func (g *Graph) withoutNodes(removable []int, kept []int, nodes QueryParams) (*Graph, QueryParams) {
+	removed := make(map[int]bool)
+	for _, v := range removable {
+		removed[v] = true
+	}
+	for _, v := range kept {
+		removed[v] = false
+	}
+	number := make([]int, g.Nodes)
+	h := *g
+	h.Nodes = 0
+	h.Values = nil
+	for v := 0; v < g.Nodes; v++ {
+		if removed[v] {
+			continue
+		}
+		number[v] = h.Nodes
+		h.Nodes++
+		if g.Values != nil {
+			h.Values = append(h.Values, g.Values[v])
+		}
+	}
+	h.Edges = make([]Edge, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		if !removed[e.Src] && !removed[e.Trg] {
+			e.Src, e.Trg = number[e.Src], number[e.Trg]
+			h.Edges = append(h.Edges, e)
+		}
+	}
+	if g.Start != -1 {
+		h.Start = number[g.Start]
+	}
+	if g.End != -1 {
+		h.End = number[g.End]
+	}
+	renumbered := make(QueryParams, len(nodes))
+	for i, node := range nodes {
+		renumbered[i] = number[node]
+	}
+	return &h, renumbered
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func containsAll(units []int, wanted ...int) bool {
+	for _, w := range wanted {
+		found := false
+		for _, u := range units {
+			found = found || u == w
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDdmin(t *testing.T) {
+	units := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	tests := []struct {
+		name    string
+		units   []int
+		failing func(kept []int) bool
+		want    []int // nil when any 1-minimal subset will do
+	}{
+		{"one culprit", units, func(kept []int) bool { return containsAll(kept, 5) }, []int{5}},
+		{"two culprits", units, func(kept []int) bool { return containsAll(kept, 3, 7) }, []int{3, 7}},
+		{"two culprits in a chunk", units, func(kept []int) bool { return containsAll(kept, 0, 1) }, []int{0, 1}},
+		{"no culprit", units, func(kept []int) bool { return true }, []int{}},
+		{"every unit needed", []int{0, 1, 2, 3}, func(kept []int) bool { return len(kept) == 4 }, []int{0, 1, 2, 3}},
+		{"any three", units, func(kept []int) bool { return len(kept) >= 3 }, nil},
+		{"one unit", []int{4}, func(kept []int) bool { return containsAll(kept, 4) }, []int{4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept := ddmin(test.units, test.failing)
+			if !test.failing(kept) {
+				t.Fatalf("failing does not hold on %v", kept)
+			}
+			if test.want != nil && !reflect.DeepEqual(kept, test.want) {
+				t.Fatalf("got %v, want %v", kept, test.want)
+			}
+			for i := range kept {
+				smaller := append(append([]int{}, kept[:i]...), kept[i+1:]...)
+				if test.failing(smaller) {
+					t.Errorf("%v is not minimal : failing still holds without %v", kept, kept[i])
+				}
+			}
+		})
+	}
+}
+
+// Whether g has an edge labeled b
+func hasBEdge(g *Graph, nodes QueryParams) bool {
+	for _, e := range g.Edges {
+		if e.Label == "b" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMinimize(t *testing.T) {
+	edges := []Edge{{0, 2, "a", 0}, {2, 3, "a", 0}, {3, 4, "b", 0}, {4, 5, "a", 0}, {5, 1, "a", 0}, {1, 6, "a", 0}, {2, 5, "a", 0}}
+	directed := &Graph{Family: LabeledGraph, Nodes: 7, Edges: edges, Start: 0, End: 1}
+	// Nodes 0 and 1 (start and end) and 6 (picked by the query) are kept, along with the nodes of the b edge
+	g, nodes := Minimize(directed, QueryParams{6}, hasBEdge)
+	if want := []Edge{{2, 3, "b", 0}}; g.Nodes != 5 || !reflect.DeepEqual(g.Edges, want) || g.Start != 0 || g.End != 1 {
+		t.Errorf("got %v nodes, edges %v, start %v and end %v, want 5 nodes, edges %v, start 0 and end 1", g.Nodes, g.Edges, g.Start, g.End, want)
+	}
+	if !reflect.DeepEqual(nodes, QueryParams{4}) {
+		t.Errorf("got query nodes %v, want [4]", nodes)
+	}
+
+	undirected := &Graph{Family: LabeledGraph, Nodes: 7, Start: 0, End: 1, Undirected: true}
+	for _, e := range edges {
+		undirected.Edges = append(undirected.Edges, e, Edge{e.Trg, e.Src, e.Label, e.Value})
+	}
+	g, _ = Minimize(undirected, QueryParams{6}, hasBEdge)
+	if want := []Edge{{2, 3, "b", 0}, {3, 2, "b", 0}}; g.Nodes != 5 || !reflect.DeepEqual(g.Edges, want) {
+		t.Errorf("got %v nodes and edges %v, want 5 nodes and edges %v, in both directions", g.Nodes, g.Edges, want)
+	}
+}
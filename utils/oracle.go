@@ -0,0 +1,337 @@
+package utils
+
+// A reference solver, answering a query on a graph in Go, given the nodes picked for the query.
+// ok is false when the instance is too large for the solver.
+type Oracle func(g *Graph, nodes QueryParams) (found bool, ok bool)
+
+// How many steps the backtracking solvers may take before giving up
+const oracleSteps = 10000000
+
+// The largest graph the hamiltonian path solver accepts, as it needs 2^n words of memory
+const oracleMaxHamiltonNodes = 22
+
+// The answer every query of the catalog is meant to give, where there is a solver for it.
+// Answers follow the problems described by the queries rather than any one formulation,
+// so that a formulation that differs from the others is caught.
+var oracles = map[string]Oracle{
+	"hamil":              hamiltonianPathFromStart,
+	"euler":              eulerianTrail,
+	"any":                anyTrail,
+	"enum":               anyTrail,
+	"tgfree":             triangleFree,
+	"tdp":                twoDisjointTrails,
+	"SmartTDP":           twoDisjointTrails,
+	"SubsetSum":          zeroSumPath,
+	"AStarBAStar":        aPlusBAPlusTrail,
+	"NormalAStarBStar":   aPlusBPlusTrail,
+	"AutomataAStarBStar": singleAOrBPlus,
+	"IncreasingPath":     nonDecreasingTrail,
+	"IncreasingNode":     increasingEdge,
+}
+
+// Returns the reference solver of the query named name, if there is one
+func FindOracle(name string) (Oracle, bool) {
+	oracle, ok := oracles[name]
+	return oracle, ok
+}
+
+// The edges of a graph as the undirected patterns of the Cypher queries see them.
+// Each edge of an undirected graph is kept once.
+type multigraph struct {
+	nodes    int
+	edges    []Edge
+	incident [][]int // the index of every edge touching each node, self loops once
+}
+
+func undirectedView(g *Graph) multigraph {
+	m := multigraph{nodes: g.Nodes, incident: make([][]int, g.Nodes)}
+	for _, e := range g.Edges {
+		if g.Undirected && e.Src > e.Trg {
+			continue
+		}
+		m.incident[e.Src] = append(m.incident[e.Src], len(m.edges))
+		if e.Src != e.Trg {
+			m.incident[e.Trg] = append(m.incident[e.Trg], len(m.edges))
+		}
+		m.edges = append(m.edges, e)
+	}
+	return m
+}
+
+// Returns the other end of edge i, seen from node
+func (m multigraph) other(i int, node int) int {
+	if m.edges[i].Src == node {
+		return m.edges[i].Trg
+	}
+	return m.edges[i].Src
+}
+
+// Whether there is a trail of at least one edge from source to target avoiding the used edges
+func (m multigraph) trailExists(source int, target int, used []bool) bool {
+	if source != target {
+		return m.reaches(source, target, used)
+	}
+	for _, i := range m.incident[source] {
+		if used[i] {
+			continue
+		}
+		if m.edges[i].Src == m.edges[i].Trg {
+			return true
+		}
+		used[i] = true
+		closed := m.reaches(m.other(i, source), source, used)
+		used[i] = false
+		if closed {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether target can be reached from source without the used edges
+func (m multigraph) reaches(source int, target int, used []bool) bool {
+	seen := make([]bool, m.nodes)
+	seen[source] = true
+	stack := []int{source}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == target {
+			return true
+		}
+		for _, i := range m.incident[node] {
+			if next := m.other(i, node); !used[i] && !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return false
+}
+
+// A path from the Start node visiting every node exactly once, of at least one edge
+func hamiltonianPathFromStart(g *Graph, nodes QueryParams) (bool, bool) {
+	n := g.Nodes
+	if n > oracleMaxHamiltonNodes || g.Start == -1 {
+		return false, false
+	}
+	if n < 2 {
+		return false, true
+	}
+	adj := make([]uint32, n)
+	for _, e := range g.Edges {
+		if e.Src != e.Trg {
+			adj[e.Src] |= 1 << e.Trg
+			adj[e.Trg] |= 1 << e.Src
+		}
+	}
+	// ends[visited] holds the last nodes of the paths from Start visiting exactly the nodes of visited
+	ends := make([]uint32, 1<<n)
+	ends[1<<g.Start] = 1 << g.Start
+	for visited := range ends {
+		for last := 0; last < n; last++ {
+			if ends[visited]&(1<<last) == 0 {
+				continue
+			}
+			for next := 0; next < n; next++ {
+				if adj[last]&^uint32(visited)&(1<<next) != 0 {
+					ends[visited|1<<next] |= 1 << next
+				}
+			}
+		}
+	}
+	return ends[1<<n-1] != 0, true
+}
+
+// A trail using every edge exactly once, of at least one edge
+func eulerianTrail(g *Graph, nodes QueryParams) (bool, bool) {
+	m := undirectedView(g)
+	if len(m.edges) == 0 {
+		return false, true
+	}
+	odd := 0
+	for node := range m.incident {
+		degree := 0
+		for _, i := range m.incident[node] {
+			degree++
+			if m.edges[i].Src == m.edges[i].Trg {
+				degree++
+			}
+		}
+		odd += degree % 2
+	}
+	if odd != 0 && odd != 2 {
+		return false, true
+	}
+	used := make([]bool, len(m.edges))
+	first := m.edges[0].Src
+	for node := range m.incident {
+		if len(m.incident[node]) > 0 && !m.reaches(first, node, used) {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// A trail of at least one edge from source to target
+func anyTrail(g *Graph, nodes QueryParams) (bool, bool) {
+	m := undirectedView(g)
+	return m.trailExists(nodes[0], nodes[1], make([]bool, len(m.edges))), true
+}
+
+// No closed trail of three edges
+func triangleFree(g *Graph, nodes QueryParams) (bool, bool) {
+	m := undirectedView(g)
+	for i, e := range m.edges {
+		for _, ends := range [][2]int{{e.Src, e.Trg}, {e.Trg, e.Src}} {
+			for _, j := range m.incident[ends[1]] {
+				if j == i {
+					continue
+				}
+				third := m.other(j, ends[1])
+				for _, k := range m.incident[third] {
+					if k != i && k != j && m.other(k, third) == ends[0] {
+						return false, true
+					}
+				}
+			}
+		}
+	}
+	return true, true
+}
+
+// Two trails from source1 to target1 and from source2 to target2 that share no edge
+func twoDisjointTrails(g *Graph, nodes QueryParams) (bool, bool) {
+	m := undirectedView(g)
+	used := make([]bool, len(m.edges))
+	steps := 0
+	var search func(node int, length int) (bool, bool)
+	search = func(node int, length int) (bool, bool) {
+		if steps++; steps > oracleSteps {
+			return false, false
+		}
+		if length > 0 && node == nodes[1] && m.trailExists(nodes[2], nodes[3], used) {
+			return true, true
+		}
+		for _, i := range m.incident[node] {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			found, ok := search(m.other(i, node), length+1)
+			used[i] = false
+			if found || !ok {
+				return found, ok
+			}
+		}
+		return false, true
+	}
+	return search(nodes[0], 0)
+}
+
+// A directed path from Start to End whose edge values sum to 0
+func zeroSumPath(g *Graph, nodes QueryParams) (bool, bool) {
+	if g.Start == -1 || g.End == -1 {
+		return false, false
+	}
+	// Walks are followed for as many steps as there are edges, which covers every path
+	type state struct{ node, sum int }
+	level := map[state]bool{{g.Start, 0}: true}
+	for step := 0; step < len(g.Edges) && len(level) > 0; step++ {
+		if len(level) > oracleSteps {
+			return false, false
+		}
+		next := make(map[state]bool)
+		for s := range level {
+			for _, e := range g.Edges {
+				if e.Src == s.node {
+					next[state{e.Trg, s.sum + e.Value}] = true
+				}
+			}
+		}
+		if next[state{g.End, 0}] {
+			return true, true
+		}
+		level = next
+	}
+	return false, true
+}
+
+// A directed trail from Start to End made of a-edges, one b-edge, then a-edges, with at least one a-edge on each side
+func aPlusBAPlusTrail(g *Graph, nodes QueryParams) (bool, bool) {
+	if g.Start == -1 || g.End == -1 {
+		return false, false
+	}
+	used := make([]bool, len(g.Edges))
+	steps := 0
+	var search func(node int, afterB bool, as int) (bool, bool)
+	search = func(node int, afterB bool, as int) (bool, bool) {
+		if steps++; steps > oracleSteps {
+			return false, false
+		}
+		if afterB && as > 0 && node == g.End {
+			return true, true
+		}
+		for i, e := range g.Edges {
+			if used[i] || e.Src != node || (e.Label == "b" && (afterB || as == 0)) {
+				continue
+			}
+			used[i] = true
+			var found, ok bool
+			if e.Label == "b" {
+				found, ok = search(e.Trg, true, 0)
+			} else {
+				found, ok = search(e.Trg, afterB, as+1)
+			}
+			used[i] = false
+			if found || !ok {
+				return found, ok
+			}
+		}
+		return false, true
+	}
+	return search(g.Start, false, 0)
+}
+
+// A trail of a-edges followed by b-edges, ignoring directions, with at least one of each
+func aPlusBPlusTrail(g *Graph, nodes QueryParams) (bool, bool) {
+	touchesA := make([]bool, g.Nodes)
+	for _, e := range g.Edges {
+		if e.Label == "a" {
+			touchesA[e.Src], touchesA[e.Trg] = true, true
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Label == "b" && (touchesA[e.Src] || touchesA[e.Trg]) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// A path accepted by the automaton of AutomataAStarBStar : a single a-edge, or b-edges only
+func singleAOrBPlus(g *Graph, nodes QueryParams) (bool, bool) {
+	return len(g.Edges) > 0, true
+}
+
+// A directed trail of at least two edges whose values never decrease
+func nonDecreasingTrail(g *Graph, nodes QueryParams) (bool, bool) {
+	for i, first := range g.Edges {
+		for j, second := range g.Edges {
+			if i != j && first.Trg == second.Src && second.Value >= first.Value {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// An edge to a node of greater value
+func increasingEdge(g *Graph, nodes QueryParams) (bool, bool) {
+	for _, e := range g.Edges {
+		if g.Values[e.Src] < g.Values[e.Trg] {
+			return true, true
+		}
+	}
+	return false, true
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "fmt"
+
+// Returns the generator of the graphs of family f to load in every backend of backends, so that their results can be paired.
+// Random graphs are generated undirected, as for the SQL queries ; see PairedView for how they are loaded in neo4j and memgraph.
+func PairedGenerator(f GraphFamily, backends []Backend) (GraphGenerator, error) {
+	if f == RandomGraph {
+		return GenerateRandomUndirectedGraph, nil
+	}
+	var generator GraphGenerator
+	for _, b := range backends {
+		gen, ok := f.Generator(b)
+		if !ok {
+			return nil, fmt.Errorf("there is no %v graph for %v", f, b)
+		}
+		generator = gen
+	}
+	if generator == nil {
+		return nil, fmt.Errorf("no backend to generate %v graphs for", f)
+	}
+	return generator, nil
+}
+
+// Returns g as it is loaded in backend b when results are paired, or when g is drawn from a corpus.
+// SQL tables hold both directions of every undirected edge, while the Cypher queries on random graphs
+// match edges in both directions : neo4j and memgraph get each undirected edge once, so that every backend sees the same graph.
+func PairedView(g *Graph, b Backend) *Graph {
+	if !g.Undirected || b == Postgres || b == DuckDB {
+		return g
+	}
+	view := *g
+	view.Undirected = false
+	view.Edges = make([]Edge, 0, len(g.Edges)/2+1)
+	for _, e := range g.Edges {
+		if e.Src <= e.Trg {
+			view.Edges = append(view.Edges, e)
+		}
+	}
+	return &view
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The statements of the scripts creating graphs, current and older ones
+var (
+	cypherNode      = regexp.MustCompile(`^CREATE \(\{name:\s*(\d+)(?:,\s*val:\s*(-?\d+))?\}\)$`)
+	cypherEdge      = regexp.MustCompile(`^MATCH \(v1\s*\{name:\s*(\d+)\}\)\s*MATCH \(v2\s*\{name:\s*(\d+)\}\)\s*CREATE \(v1\)-\[:(\w+)(?:\s*\{(value|val):\s*(-?\d+)\})?\]->\(v2\)$`)
+	cypherLabel     = regexp.MustCompile(`^MATCH \(n\s*\{name:\s*(\d+)\}\)\s*SET n\s*:(Start|End)$`)
+	sqlEdge         = regexp.MustCompile(`^INSERT INTO G VALUES \((\d+),\s*(\d+)(?:,\s*(-?\d+))?\)$`)
+	sqlNode         = regexp.MustCompile(`^INSERT INTO V VALUES \((\d+),\s*[^,]+,\s*(NULL|-?\d+),\s*(true|false),\s*(true|false)\)$`)
+	sqlLabeledEdge  = regexp.MustCompile(`^INSERT INTO (A|B) \(s, t\) VALUES \((\d+),\s*(\d+)\)$`)
+	sqlLabel        = regexp.MustCompile(`^INSERT INTO (StartLabel|EndLabel) VALUES \((\d+)\)$`)
+	schemaStatement = regexp.MustCompile(`^(DROP|CREATE TABLE|CREATE OR REPLACE SEQUENCE)\b`)
+)
+
+// Returns the graph created by a Cypher or SQL script, as written to dump files.
+// Nodes that are not named by the script, such as isolated nodes of SQL scripts without a node table, are left out.
+func ParseGraphScript(script []string) (*Graph, error) {
+	g := newGraph(RandomGraph, 0)
+	values := make(map[int]int)
+	weighted, edgeValues, sql := false, false, false
+	addNode := func(node int) {
+		if node >= g.Nodes {
+			g.Nodes = node + 1
+		}
+	}
+	for _, line := range script {
+		for _, statement := range strings.Split(line, ";") {
+			statement = strings.TrimSpace(statement)
+			var match []string
+			switch {
+			case statement == "" || schemaStatement.MatchString(statement):
+			case cypherNode.MatchString(statement):
+				match = cypherNode.FindStringSubmatch(statement)
+				node := atoi(match[1])
+				addNode(node)
+				if match[2] != "" {
+					values[node] = atoi(match[2])
+				}
+			case cypherEdge.MatchString(statement):
+				match = cypherEdge.FindStringSubmatch(statement)
+				e := Edge{Src: atoi(match[1]), Trg: atoi(match[2]), Label: match[3]}
+				if match[5] != "" {
+					e.Value = atoi(match[5])
+					weighted = weighted || match[4] == "value"
+					edgeValues = edgeValues || match[4] == "val"
+				}
+				g.addEdge(e, addNode)
+			case cypherLabel.MatchString(statement):
+				match = cypherLabel.FindStringSubmatch(statement)
+				addNode(atoi(match[1]))
+				g.setLabel(match[2], atoi(match[1]))
+			case sqlEdge.MatchString(statement):
+				match = sqlEdge.FindStringSubmatch(statement)
+				e := Edge{Src: atoi(match[1]), Trg: atoi(match[2]), Label: "Edge"}
+				if match[3] != "" {
+					e.Value = atoi(match[3])
+					weighted = true
+				}
+				g.addEdge(e, addNode)
+				sql = true
+			case sqlNode.MatchString(statement):
+				match = sqlNode.FindStringSubmatch(statement)
+				node := atoi(match[1])
+				addNode(node)
+				if match[2] != "NULL" {
+					values[node] = atoi(match[2])
+				}
+				if match[3] == "true" {
+					g.Start = node
+				}
+				if match[4] == "true" {
+					g.End = node
+				}
+			case sqlLabeledEdge.MatchString(statement):
+				match = sqlLabeledEdge.FindStringSubmatch(statement)
+				g.addEdge(Edge{Src: atoi(match[2]), Trg: atoi(match[3]), Label: strings.ToLower(match[1])}, addNode)
+			case sqlLabel.MatchString(statement):
+				match = sqlLabel.FindStringSubmatch(statement)
+				addNode(atoi(match[2]))
+				g.setLabel(strings.TrimSuffix(match[1], "Label"), atoi(match[2]))
+			default:
+				return nil, fmt.Errorf("unrecognized statement in graph script : %v", statement)
+			}
+		}
+	}
+
+	switch {
+	case g.hasLabel("a") || g.hasLabel("b"):
+		g.Family = LabeledGraph
+	case weighted:
+		g.Family = DoubleLineGraph
+	case edgeValues:
+		g.Family = EdgeValueGraph
+	case len(values) > 0:
+		g.Family = NodeValueGraph
+		g.Values = make([]int, g.Nodes)
+		for node, value := range values {
+			g.Values[node] = value
+		}
+	default:
+		g.Undirected = sql && g.symmetric()
+	}
+	return g, nil
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+func (g *Graph) addEdge(e Edge, addNode func(int)) {
+	addNode(e.Src)
+	addNode(e.Trg)
+	g.Edges = append(g.Edges, e)
+}
+
+func (g *Graph) setLabel(label string, node int) {
+	if label == "Start" {
+		g.Start = node
+	} else {
+		g.End = node
+	}
+}
+
+func (g *Graph) hasLabel(label string) bool {
+	for _, e := range g.Edges {
+		if e.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether every edge of g comes with its reverse edge
+func (g *Graph) symmetric() bool {
+	edges := make(map[[2]int]bool)
+	for _, e := range g.Edges {
+		edges[[2]int{e.Src, e.Trg}] = true
+	}
+	for _, e := range g.Edges {
+		if !edges[[2]int{e.Trg, e.Src}] {
+			return false
+		}
+	}
+	return true
+}
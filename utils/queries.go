@@ -2,22 +2,21 @@ package utils
 
 import (
 	"fmt"
-	"math/rand"
 )
 
 //Cypher
 
-func RandomTwoDisjointPathQuery(n int) string {
+func TwoDisjointPathQuery(s1 int, t1 int, s2 int, t2 int) string {
 	return fmt.Sprintf(`MATCH p1 = (s1 {name: %d})-[:Edge*]-(t1 {name: %d})
     MATCH p2 = (s2 {name: %d})-[:Edge*]-(t2 {name: %d})
     WHERE none(r in relationships(p2) WHERE r in relationships(p1))
-    RETURN p1, p2 LIMIT 1`, rand.Intn(n), rand.Intn(n), rand.Intn(n), rand.Intn(n))
+    RETURN p1, p2 LIMIT 1`, s1, t1, s2, t2)
 }
 
-func SmartRandomTwoDisjointPathQuery(n int) string {
+func SmartTwoDisjointPathQuery(s1 int, t1 int, s2 int, t2 int) string {
 	return fmt.Sprintf(`MATCH p1 = (s1 {name: %d})-[:Edge*]-(t1 {name: %d}),
 	p2 = (s2 {name: %d})-[:Edge*]-(t2 {name: %d})
-	RETURN p1, p2 LIMIT 1`, rand.Intn(n), rand.Intn(n), rand.Intn(n), rand.Intn(n))
+	RETURN p1, p2 LIMIT 1`, s1, t1, s2, t2)
 }
 
 func HamiltonianPathMemgraph() string {
@@ -40,14 +39,14 @@ func HamiltonianPath() string {
   RETURN path LIMIT 1`
 }
 
-func EnumeratePaths(n int) string {
+func EnumeratePaths(source int, target int) string {
 	return fmt.Sprintf(`MATCH p = ({name: %d})-[:Edge*]-({name: %d})
-		RETURN count(p)`, rand.Intn(n), rand.Intn(n))
+		RETURN count(p)`, source, target)
 }
 
-func FindAnyPath(n int) string {
+func FindAnyPath(source int, target int) string {
 	return fmt.Sprintf(`MATCH p = ({name: %d})-[:Edge*]-({name: %d})
-		RETURN p LIMIT 1`, rand.Intn(n), rand.Intn(n))
+		RETURN p LIMIT 1`, source, target)
 }
 
 func TriangleFree() string {
@@ -151,28 +150,29 @@ func IncreasingPathNode() string {
 
 //SQL
 
-func SubsetSumSQL(n int) string {
-	return fmt.Sprintf(`explain analyze with recursive paths(source, target, path, total_weight)                   
+func SubsetSumSQL() string {
+	return `explain analyze with recursive paths(source, target, path, total_weight)                   
 	AS (SELECT src as source, trg as target, ARRAY[src,weight,trg] as path, weight as total_weight
 		FROM G
-		WHERE src = 0
+		WHERE src IN (SELECT id FROM V WHERE is_start)
 		UNION
 		SELECT source, trg, array_append(array_append(path,weight),trg), total_weight+weight as total_weight	
 		FROM G, paths
 		WHERE src=target)
 	SELECT *
-	FROM paths WHERE total_weight=0 and source=0 and target=%d;`, n-1)
+	FROM paths WHERE total_weight=0 and target IN (SELECT id FROM V WHERE is_end);`
 }
 
 func HamiltonianSQL() string {
 	return `explain analyze with recursive paths(startP, endP, path)                   
 	AS (SELECT src as startP, trg as endP, ARRAY[src,trg] as path
 		FROM G
+		WHERE src IN (SELECT id FROM V WHERE is_start) AND src <> trg
 		UNION
 		SELECT startP, trg, array_append(path,trg)	
 		FROM G, paths
 		WHERE src=endP AND trg <> ALL(path))
-	SELECT * FROM paths WHERE ARRAY_LENGTH(path,1) = (SELECT COUNT(distinct src) FROM G)
+	SELECT * FROM paths WHERE ARRAY_LENGTH(path,1) = (SELECT COUNT(*) FROM V)
 	LIMIT 1;`
 }
 
@@ -184,7 +184,7 @@ func EulerianSQL() string {
 		SELECT startP, trg, array_append(path,(src,trg))	
 		FROM G, paths
 		WHERE src=endP AND (src,trg) <> ALL(path) AND  (trg,src) <> ALL(path))
-	SELECT * FROM paths WHERE ARRAY_LENGTH(path,1) = (SELECT COUNT(*)/2 FROM G)
+	SELECT * FROM paths WHERE ARRAY_LENGTH(path,1) = (SELECT COUNT(*) FROM G WHERE src <= trg)
 	LIMIT 1;`
 }
 
@@ -193,7 +193,7 @@ func AStarBAStarSQL() string {
 		SELECT s, t, 0 AS depth, array[s,t] AS path,
 				array[s||'.'|| t] AS edges FROM A
 		UNION
-		SELECT A.s, A.t, a_kleene_star.depth+1,
+		SELECT a_kleene_star.s, A.t, a_kleene_star.depth+1,
 				a_kleene_star.path||A.t,
 				a_kleene_star.edges ||
 				concat(A.s||'.',A.t)
@@ -202,9 +202,9 @@ func AStarBAStarSQL() string {
 		NOT concat(A.s||'.',A.t)=any(a_kleene_star.edges)
 	)
 	SELECT A1.s, A2.t
-	FROM a_kleene_star A1, a_kleene_star A2, B, StartLabel, EndLabel
-	WHERE A1.t=B.s AND B.t=A2.s AND A1.s=StartLabel.node AND
-		A2.t=EndLabel.node AND NOT (A1.edges && A2.edges) 
+	FROM a_kleene_star A1, a_kleene_star A2, B, V StartNode, V EndNode
+	WHERE A1.t=B.s AND B.t=A2.s AND A1.s=StartNode.id AND StartNode.is_start AND
+		A2.t=EndNode.id AND EndNode.is_end AND NOT (A1.edges && A2.edges) 
 	LIMIT 1;`
 }
 
@@ -225,7 +225,7 @@ func AStarBAStarDuckDB() string {
 		SELECT s, t, 0 AS depth, array[s,t] AS path,
 				array[s||'.'|| t] AS edges FROM A
 		UNION
-		SELECT A.s::VARCHAR, A.t::VARCHAR, a_kleene_star.depth+1,
+		SELECT a_kleene_star.s::VARCHAR, A.t::VARCHAR, a_kleene_star.depth+1,
 				a_kleene_star.path|| ARRAY[A.t],
 				 CONCAT(a_kleene_star.edges, ARRAY[A.s || '.' || A.t])
 		FROM A , a_kleene_star
@@ -233,8 +233,8 @@ func AStarBAStarDuckDB() string {
 		NOT concat(A.s||'.',A.t) IN (SELECT UNNEST(a_kleene_star.edges))
 	)
 	SELECT A1.s, A2.t
-	FROM a_kleene_star A1, a_kleene_star A2, B, StartLabel, EndLabel
-	WHERE A1.t=B.s AND B.t=A2.s AND A1.s=StartLabel.node AND
-		A2.t=EndLabel.node AND NOT (A1.edges && A2.edges) 
+	FROM a_kleene_star A1, a_kleene_star A2, B, V StartNode, V EndNode
+	WHERE A1.t=B.s AND B.t=A2.s AND A1.s=StartNode.id AND StartNode.is_start AND
+		A2.t=EndNode.id AND EndNode.is_end AND NOT (A1.edges && A2.edges) 
 	LIMIT 1;`
 }
\ No newline at end of file
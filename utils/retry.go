@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// The kinds of errors a query run or a graph load can fail with
+type ErrorClass string
+
+const (
+	ConnectivityError ErrorClass = "connectivity" // the connection to the database was refused, reset or lost
+	TransientError    ErrorClass = "transient"    // deadlocks, serialization failures and lock contention, that may succeed when tried again
+	OtherError        ErrorClass = "other"        // everything else, such as syntax errors
+)
+
+var ErrorClasses = []ErrorClass{ConnectivityError, TransientError, OtherError}
+
+func ParseErrorClass(name string) (ErrorClass, error) {
+	for _, c := range ErrorClasses {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown error class %q, expected one of %v", name, ErrorClasses)
+}
+
+// Returns the class of an error returned by neo4j, memgraph, postgres or duckDB
+func ClassifyError(err error) ErrorClass {
+	var neo4jErr *neo4j.Neo4jError
+	var connectivityErr *neo4j.ConnectivityError
+	var pgErr *pgconn.PgError
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled): // timeouts and interruptions are results, not failures
+		return OtherError
+	case errors.As(err, &connectivityErr), errors.As(err, &connectErr), errors.As(err, &netErr),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), strings.Contains(err.Error(), "connection reset"), strings.Contains(err.Error(), "broken pipe"):
+		return ConnectivityError
+	case errors.As(err, &neo4jErr) && strings.HasPrefix(neo4jErr.Code, "Neo.TransientError"):
+		return TransientError
+	case errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01" || pgErr.Code == "55P03"): // serialization failure, deadlock, lock not available
+		return TransientError
+	case strings.Contains(err.Error(), "Could not set lock on file"), strings.Contains(err.Error(), "Conflicting lock"), strings.Contains(err.Error(), "Transaction conflict"): // duckDB
+		return TransientError
+	default:
+		return OtherError
+	}
+}
+
+// How failed graph loads and query runs are tried again
+type RetryPolicy struct {
+	MaxAttempts int           // including the first attempt, so 1 never retries
+	Backoff     time.Duration // the wait before the first retry, doubled before each following one
+	Classes     []ErrorClass  // the classes of errors that are retried
+}
+
+func (r RetryPolicy) retries(err error) bool {
+	class := ClassifyError(err)
+	for _, c := range r.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Calls f until it succeeds, fails with an error the policy does not retry, ctx is canceled or MaxAttempts attempts were made.
+// Returns the number of retries and the last error of f.
+func (r RetryPolicy) Do(ctx context.Context, f func() error) (int, error) {
+	wait := r.Backoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= r.MaxAttempts || !r.retries(err) {
+			return attempt - 1, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempt - 1, err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// A workload is a query defined outside of the program, in a directory holding:
+//   - <name>.json : {"description": ..., "graph": ..., "answer": ..., "complexity": ...}
+//   - <name>.cypher : the query run on neo4j and memgraph
+//   - <name>.sql : the query run on postgres and duckDB, without explain analyze
+//   - <name>.<backend>.cypher or <name>.<backend>.sql : a query for one backend only,
+//     taking precedence over the ones above (e.g. any.memgraph.cypher)
+//
+// Query texts are go templates in which {{n}} is the number of nodes of the graph,
+// {{startNode}} and {{endNode}} are two nodes drawn at random for each query,
+// recorded as its source and target parameters, and each {{randomNode}} is another node
+// drawn uniformly for each query, recorded as its random1, random2... parameters.
+
+type workloadSpec struct {
+	Description string      `json:"description"`
+	Graph       GraphFamily `json:"graph"`
+	Answer      string      `json:"answer"`
+	Complexity  string      `json:"complexity"`
+}
+
+var answerNames = map[string]Answer{
+	"anyRow":        AnyRow,
+	"boolValue":     BoolValue,
+	"positiveCount": PositiveCount,
+}
+
+// Loads every workload of directory dir
+func LoadWorkloads(dir string) ([]Query, error) {
+	specFiles, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	workloads := make([]Query, 0)
+	for _, specFile := range specFiles {
+		workload, err := loadWorkload(dir, strings.TrimSuffix(filepath.Base(specFile), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("workload %v : %w", specFile, err)
+		}
+		workloads = append(workloads, workload)
+	}
+	return workloads, nil
+}
+
+func loadWorkload(dir string, name string) (Query, error) {
+	content, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var spec workloadSpec
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, err
+	}
+	if _, ok := graphGenerators[spec.Graph]; !ok {
+		return nil, fmt.Errorf("unknown graph family %q", spec.Graph)
+	}
+	answer, ok := answerNames[spec.Answer]
+	if !ok {
+		return nil, fmt.Errorf("unknown answer %q, expected anyRow, boolValue or positiveCount", spec.Answer)
+	}
+
+	templates := make(map[Backend]*template.Template)
+	var endpoints []string
+	randomNodes := 0
+	for _, b := range []Backend{Neo4j, Memgraph, Postgres, DuckDB} {
+		extension := ".cypher"
+		if b == Postgres || b == DuckDB {
+			extension = ".sql"
+		}
+		text, err := os.ReadFile(filepath.Join(dir, name+"."+string(b)+extension))
+		if errors.Is(err, os.ErrNotExist) {
+			text, err = os.ReadFile(filepath.Join(dir, name+extension))
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		queryText := string(text)
+		if b == Postgres {
+			if answer != AnyRow { // postgres queries only report how many rows they return, through explain analyze
+				return nil, fmt.Errorf("postgres only supports anyRow answers, not %v : name the query %v.duckDB.sql to run it on duckDB only", spec.Answer, name)
+			}
+			queryText = "explain analyze " + queryText
+		}
+		tmpl, err := template.New(name).Funcs(templateFuncs(0, nil, 0)).Parse(queryText)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(queryText, "startNode") || strings.Contains(queryText, "endNode") {
+			endpoints = sourceTarget
+		}
+		if count := strings.Count(queryText, "randomNode"); count > randomNodes {
+			randomNodes = count
+		}
+		templates[b] = tmpl
+	}
+	if len(templates) == 0 {
+		return nil, errors.New("no .cypher or .sql query found")
+	}
+	renderers := make(map[Backend]Renderer)
+	for b, tmpl := range templates {
+		renderers[b] = templateRenderer(tmpl, len(endpoints))
+	}
+
+	return catalogQuery{
+		name:        name,
+		description: spec.Description,
+		graph:       spec.Graph,
+		endpoints:   endpoints,
+		randomNodes: randomNodes,
+		renderers:   renderers,
+		answer:      answer,
+		complexity:  spec.Complexity,
+	}, nil
+}
+
+// Returns the placeholders of a query on a graph of n nodes with the given nodes,
+// the random nodes following the first endpoints ones, in the order the placeholders appear.
+func templateFuncs(n int, nodes []int, endpoints int) template.FuncMap {
+	next := endpoints
+	return template.FuncMap{
+		"n":         func() int { return n },
+		"startNode": func() int { return nodes[0] },
+		"endNode":   func() int { return nodes[1] },
+		"randomNode": func() (int, error) {
+			if next >= len(nodes) {
+				return 0, errors.New("randomNode is used more times than it appears in the query")
+			}
+			next++
+			return nodes[next-1], nil
+		},
+	}
+}
+
+func templateRenderer(tmpl *template.Template, endpoints int) Renderer {
+	return func(n int, nodes []int) (string, error) {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			return "", err
+		}
+		var query strings.Builder
+		err = clone.Funcs(templateFuncs(n, nodes, endpoints)).Execute(&query, nil)
+		return query.String(), err
+	}
+}
@@ -0,0 +1,2 @@
+MATCH p = ({name: {{startNode}}})-[:Edge*]-({name: {{endNode}}})
+RETURN p LIMIT 1
@@ -0,0 +1,8 @@
+WITH RECURSIVE reached(node) AS (
+	SELECT {{startNode}}
+	UNION
+	SELECT trg
+	FROM G, reached
+	WHERE src = node)
+SELECT * FROM reached WHERE node = {{endNode}}
+LIMIT 1;